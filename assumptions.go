@@ -0,0 +1,75 @@
+// Solving under assumptions, with the subset of failed assumptions on unsat.
+
+package s1t
+
+import (
+	"context"
+	"fmt"
+)
+
+// SolveAssuming is like Solve, but with the assumptions fixed to true for this
+// call only. If the problem is unsat under the assumptions, but maybe not
+// without them, the Unsat solution lists in Failed the assumptions which led
+// to the final conflict. Solution.Failed is empty when the problem is unsat
+// regardless of the assumptions.
+func (s *Solver) SolveAssuming(assumptions []Literal) (Solution, error) {
+	return s.SolveAssumingContext(context.Background(), assumptions)
+}
+
+// SolveAssumingContext is like SolveAssuming, but also gives up with an Unknown
+// solution and the context's error if ctx is done first.
+func (s *Solver) SolveAssumingContext(ctx context.Context, assumptions []Literal) (Solution, error) {
+	for _, l := range assumptions {
+		if int(l.Var()) >= s.NumVars() {
+			return unknown(), fmt.Errorf("Assumption %v goes beyond the %d vars", l, s.NumVars())
+		}
+	}
+	s.restore(assumptions)
+	return s.solve(ctx, assumptions)
+}
+
+// assume decides on assumption p, at the decision level of its index among
+// the assumptions. If p is already true, the level is left empty. Returns false
+// if p is already false, after setting the failed assumptions.
+func (s *Solver) assume(p Literal) bool {
+	if s.isFalse(p) {
+		s.failed = s.analyzeFinal(p)
+		return false
+	}
+	s.trail.newDecisionLevel()
+	if !s.isTrue(p) {
+		s.stats.Decisions++
+		s.enqueue(p, noReason)
+	}
+	return true
+}
+
+// analyzeFinal returns p and the assumptions that imply its negation, by
+// following the reasons of the assignments back to the decisions. All
+// decisions are assumptions, since it is called while deciding them.
+func (s *Solver) analyzeFinal(p Literal) []Literal {
+	failed := []Literal{p}
+	t := &s.trail
+	if t.level[p.Var()] == 0 {
+		return failed
+	}
+	s.seen[p.Var()] = true
+	for i := len(t.literals) - 1; i >= t.levelStarts[0]; i-- {
+		l := t.literals[i]
+		v := l.Var()
+		if !s.seen[v] {
+			continue
+		}
+		s.seen[v] = false
+		if t.reason[v] == noReason {
+			failed = append(failed, l)
+			continue
+		}
+		for _, q := range s.clauses[s.reasonOf(v)].Literals {
+			if q.Var() != v && t.level[q.Var()] > 0 {
+				s.seen[q.Var()] = true
+			}
+		}
+	}
+	return failed
+}
@@ -0,0 +1,116 @@
+package s1t
+
+import (
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSolveAssuming(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 4 3",
+		"-1 2 0",
+		"-2 3 0",
+		"-4 -2 3 0",
+	}, t)
+	cases := []struct {
+		assumptions []Literal
+		failed      []Literal // nil if sat
+	}{
+		{[]Literal{Positive(0), Positive(2)}, nil},
+		{[]Literal{Positive(0), Negative(2)}, []Literal{Negative(2), Positive(0)}},
+		// The unrelated assumption 4 is not part of the failed ones.
+		{[]Literal{Positive(3), Positive(0), Negative(2)}, []Literal{Negative(2), Positive(0)}},
+		{[]Literal{Negative(2), Positive(0)}, []Literal{Positive(0), Negative(2)}},
+		{[]Literal{Positive(1), Negative(1)}, []Literal{Negative(1), Positive(1)}},
+	}
+	s := NewSolver(problem, Options{})
+	for _, c := range cases {
+		solution, err := s.SolveAssuming(c.assumptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.failed == nil {
+			if solution.Status != Sat {
+				t.Errorf("Assuming %v, expected sat but got %v", c.assumptions, solution)
+			}
+			continue
+		}
+		if solution.Status != Unsat || !cmp.Equal(solution.Failed, c.failed) {
+			t.Errorf("Assuming %v, expected unsat with failed %v, but got %v",
+				c.assumptions, c.failed, solution)
+		}
+	}
+	// The assumptions didn't stick.
+	if solution, _ := s.Solve(); solution.Status != Sat {
+		t.Errorf("Expected sat without assumptions, but got %v", solution)
+	}
+}
+
+func TestSolveAssumingAtLevelZero(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 2 2",
+		"-1 0",
+		"1 2 0",
+	}, t)
+	s := NewSolver(problem, Options{})
+	solution, _ := s.SolveAssuming([]Literal{Negative(1), Positive(0)})
+	if solution.Status != Unsat || !cmp.Equal(solution.Failed, []Literal{Negative(1)}) {
+		t.Errorf("Expected unsat with failed -2, but got %v", solution)
+	}
+	if _, err := s.SolveAssuming([]Literal{Positive(2)}); err == nil {
+		t.Error("Expected an error for an undeclared variable")
+	}
+	s.AddClause(Negative(1))
+	solution, _ = s.SolveAssuming([]Literal{Positive(1)})
+	if solution.Status != Unsat || len(solution.Failed) != 0 {
+		t.Errorf("Expected unsat regardless of the assumptions, but got %v", solution)
+	}
+}
+
+func TestFailedAssumptionsAreUnsat(t *testing.T) {
+	input, err := os.Open("test_cnf/RTI_k3_n100_m429_499.cnf")
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	defer input.Close()
+	problem := parseOrDie(input, t)
+	s := NewSolver(problem, Options{})
+	rng := rand.New(rand.NewSource(1))
+	var unsats int
+	for i := 0; i < 50; i++ {
+		var assumptions []Literal
+		for _, v := range rng.Perm(problem.Spec.NumVariables)[:12] {
+			if rng.Intn(2) == 0 {
+				assumptions = append(assumptions, Positive(VarNum(v)))
+			} else {
+				assumptions = append(assumptions, Negative(VarNum(v)))
+			}
+		}
+		solution, err := s.SolveAssuming(assumptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if solution.Status == Sat {
+			for _, l := range assumptions {
+				if solution.Assignment[l.Var()] != l.AsInt() {
+					t.Fatalf("Solution %v doesn't satisfy assumption %v", solution, l)
+				}
+			}
+			continue
+		}
+		unsats++
+		if len(solution.Failed) == 0 {
+			t.Fatalf("Expected failed assumptions among %v", assumptions)
+		}
+		again, _ := s.SolveAssuming(solution.Failed)
+		if again.Status != Unsat {
+			t.Fatalf("Failed assumptions %v are not unsat: %v", solution.Failed, again)
+		}
+	}
+	if unsats == 0 {
+		t.Error("Expected some unsat assumptions")
+	}
+}
@@ -0,0 +1,175 @@
+// Package cardinality encodes constraints on the number of true literals, such
+// as "at most k of these", as clauses (or keeps them as native constraints).
+package cardinality
+
+import (
+	"github.com/jvoung/s1t"
+)
+
+// Encoding is a way to encode cardinality constraints as clauses.
+type Encoding int
+
+const (
+	// Pairwise has a clause for each set of k+1 literals, saying that one of
+	// them is false: the pairs of literals for at-most-one. It needs no new vars,
+	// but the number of clauses grows quickly with k.
+	Pairwise Encoding = iota
+	// SequentialCounter counts the true literals in unary, one literal after
+	// another (Sinz 2005).
+	SequentialCounter
+	// Commander splits the literals into small groups, with commander vars that
+	// count the true literals of their group, and then constrains the
+	// commanders (Klieber and Kwon 2007, generalized by Frisch and Giannaros).
+	// Each group needs about C(2k+2, k+1) clauses, so for k > 2 it uses the
+	// SequentialCounter instead.
+	Commander
+	// Totalizer counts the true literals in unary, by a tree of unary adders
+	// (Bailleux and Boufkhad 2003).
+	Totalizer
+	// CardinalityNetwork sorts blocks of the literals by odd-even merge
+	// networks, and merges the blocks keeping only the k+1 largest, with only
+	// the clauses for the direction which the constraint needs (Asín et al.
+	// 2011).
+	CardinalityNetwork
+	// Ladder encodes at-most-one by a ladder of vars, where each var implies the
+	// previous one (Gent and Nightingale 2004). For k > 1, it uses a ladder for
+	// each count, which is the SequentialCounter.
+	Ladder
+	// Native keeps the constraints as the problem's AtMosts, which the solver
+	// propagates directly, instead of encoding them as clauses.
+	Native
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case Pairwise:
+		return "pairwise"
+	case SequentialCounter:
+		return "sequential"
+	case Commander:
+		return "commander"
+	case Totalizer:
+		return "totalizer"
+	case CardinalityNetwork:
+		return "network"
+	case Ladder:
+		return "ladder"
+	case Native:
+		return "native"
+	default:
+		return "unknown"
+	}
+}
+
+// Encodings lists the encodings, for looking them up by name.
+var Encodings = []Encoding{Pairwise, SequentialCounter, Commander, Totalizer, CardinalityNetwork, Ladder, Native}
+
+// Encoder collects clauses for a problem's vars, and the cardinality
+// constraints over them in an encoding. The encodings add vars after the
+// problem's vars.
+type Encoder struct {
+	encoding Encoding
+	numVars  int
+	nextVar  s1t.VarNum
+	clauses  []s1t.Clause
+	atMosts  []s1t.AtMost
+}
+
+// NewEncoder returns an encoder for a problem with numVars vars.
+func NewEncoder(encoding Encoding, numVars int) *Encoder {
+	return &Encoder{encoding: encoding, numVars: numVars, nextVar: s1t.VarNum(numVars)}
+}
+
+// AddClause adds a plain clause.
+func (e *Encoder) AddClause(literals ...s1t.Literal) {
+	e.clauses = append(e.clauses, s1t.Clause{Literals: literals})
+}
+
+// AtMost adds clauses which say that at most k of the literals are true.
+func (e *Encoder) AtMost(literals []s1t.Literal, k int) {
+	switch {
+	case k < 0:
+		e.AddClause()
+	case k >= len(literals):
+	case k == 0:
+		for _, l := range literals {
+			e.AddClause(l.Negate())
+		}
+	case k == len(literals)-1:
+		// One of the literals is false.
+		negated := make([]s1t.Literal, len(literals))
+		for i, l := range literals {
+			negated[i] = l.Negate()
+		}
+		e.AddClause(negated...)
+	default:
+		switch e.encoding {
+		case Pairwise:
+			e.pairwise(literals, k)
+		case SequentialCounter:
+			e.sequentialCounter(literals, k)
+		case Commander:
+			e.commander(literals, k)
+		case Totalizer:
+			e.totalizer(literals, k)
+		case CardinalityNetwork:
+			e.network(literals, k)
+		case Ladder:
+			if k == 1 {
+				e.ladder(literals)
+			} else {
+				e.sequentialCounter(literals, k)
+			}
+		case Native:
+			e.atMosts = append(e.atMosts, s1t.AtMost{Literals: append([]s1t.Literal{}, literals...), K: k})
+		}
+	}
+}
+
+// AtLeast adds clauses which say that at least k of the literals are true:
+// that at most len(literals) - k of their negations are true. At least one is
+// a single clause, in every encoding.
+func (e *Encoder) AtLeast(literals []s1t.Literal, k int) {
+	negated := make([]s1t.Literal, len(literals))
+	for i, l := range literals {
+		negated[i] = l.Negate()
+	}
+	e.AtMost(negated, len(literals)-k)
+}
+
+// Exactly adds clauses which say that exactly k of the literals are true.
+func (e *Encoder) Exactly(literals []s1t.Literal, k int) {
+	e.AtMost(literals, k)
+	e.AtLeast(literals, k)
+}
+
+// Clauses returns the clauses added so far.
+func (e *Encoder) Clauses() []s1t.Clause {
+	return e.clauses
+}
+
+// NumVars returns the number of vars: the problem's vars, then those added by
+// the encodings.
+func (e *Encoder) NumVars() int {
+	return int(e.nextVar)
+}
+
+// Problem returns a problem with the clauses (and native constraints) added
+// so far.
+func (e *Encoder) Problem() s1t.Problem {
+	return s1t.Problem{
+		Spec: s1t.ProblemSpec{
+			Format:          "cnf",
+			NumVariables:    e.NumVars(),
+			NumClauses:      len(e.clauses) + len(e.atMosts),
+			NumAuxVariables: e.NumVars() - e.numVars,
+		},
+		Clauses: e.clauses,
+		AtMosts: e.atMosts,
+	}
+}
+
+func (e *Encoder) newVar() s1t.Literal {
+	e.nextVar++
+	return s1t.Positive(e.nextVar - 1)
+}
@@ -0,0 +1,128 @@
+package cardinality
+
+import (
+	"testing"
+
+	"github.com/jvoung/s1t"
+)
+
+// checkConstraint checks that the models of the problem, projected onto the
+// first n vars, are the assignments for which holds is true.
+func checkConstraint(t *testing.T, desc string, problem s1t.Problem, n int, holds func(count int) bool) {
+	expected := 0
+	for bits := 0; bits < 1<<uint(n); bits++ {
+		count := 0
+		for v := 0; v < n; v++ {
+			count += (bits >> uint(v)) & 1
+		}
+		if holds(count) {
+			expected++
+		}
+	}
+	var projection []s1t.VarNum
+	for v := 0; v < n; v++ {
+		projection = append(projection, s1t.VarNum(v))
+	}
+	found, err := s1t.NewSolver(problem, s1t.Options{}).Enumerate(projection, 0, func(solution s1t.Solution) bool {
+		count := 0
+		for _, value := range solution.Assignment[:n] {
+			count += value
+		}
+		if !holds(count) {
+			t.Errorf("%s: model %v has %d true literals", desc, solution.Assignment[:n], count)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != expected {
+		t.Errorf("%s: expected %d models, but got %d", desc, expected, found)
+	}
+}
+
+func TestEncodings(t *testing.T) {
+	for _, encoding := range Encodings {
+		for n := 0; n <= 7; n++ {
+			literals := make([]s1t.Literal, n)
+			for v := range literals {
+				literals[v] = s1t.Positive(s1t.VarNum(v))
+			}
+			for k := -1; k <= n+1; k++ {
+				k := k
+				e := NewEncoder(encoding, n)
+				e.AtMost(literals, k)
+				checkConstraint(t, encoding.String()+" at most", e.Problem(), n,
+					func(count int) bool { return count <= k })
+
+				e = NewEncoder(encoding, n)
+				e.AtLeast(literals, k)
+				checkConstraint(t, encoding.String()+" at least", e.Problem(), n,
+					func(count int) bool { return count >= k })
+
+				e = NewEncoder(encoding, n)
+				e.Exactly(literals, k)
+				checkConstraint(t, encoding.String()+" exactly", e.Problem(), n,
+					func(count int) bool { return count == k })
+			}
+		}
+	}
+}
+
+func TestNegatedLiterals(t *testing.T) {
+	for _, encoding := range Encodings {
+		e := NewEncoder(encoding, 5)
+		literals := []s1t.Literal{s1t.Negative(0), s1t.Positive(1), s1t.Negative(2), s1t.Negative(3), s1t.Positive(4)}
+		e.AtMost(literals, 2)
+		e.AddClause(s1t.Negative(1))
+		e.AddClause(s1t.Positive(2))
+		// Only v0, v3 and v4 are free, and at most 2 of ¬v0, ¬v3, v4 are true.
+		found, _ := s1t.NewSolver(e.Problem(), s1t.Options{}).Enumerate(
+			[]s1t.VarNum{0, 1, 2, 3, 4}, 0, func(s1t.Solution) bool { return true })
+		if found != 7 {
+			t.Errorf("%s: expected 7 models, but got %d", encoding, found)
+		}
+	}
+}
+
+func TestSingleClauses(t *testing.T) {
+	literals := make([]s1t.Literal, 9)
+	for v := range literals {
+		literals[v] = s1t.Positive(s1t.VarNum(v))
+	}
+	for _, encoding := range Encodings {
+		e := NewEncoder(encoding, len(literals))
+		e.AtLeast(literals, 1)
+		e.AtMost(literals, len(literals)-1)
+		if problem := e.Problem(); len(problem.Clauses) != 2 || problem.Spec.NumAuxVariables != 0 {
+			t.Errorf("%s: expected 2 clauses, but got %v", encoding, problem)
+		}
+	}
+}
+
+func TestEncodingSizes(t *testing.T) {
+	literals := make([]s1t.Literal, 100)
+	for v := range literals {
+		literals[v] = s1t.Positive(s1t.VarNum(v))
+	}
+	for _, encoding := range Encodings {
+		if encoding == Pairwise {
+			continue
+		}
+		e := NewEncoder(encoding, len(literals))
+		e.AtMost(literals, 1)
+		// The pairwise encoding needs 4950 clauses.
+		if len(e.Clauses()) > 1000 {
+			t.Errorf("%s: expected fewer clauses than pairwise, but got %d",
+				encoding, len(e.Clauses()))
+		}
+		// The pairwise encoding needs C(100, 11) clauses, and commander groups
+		// would need C(22, 11) each.
+		e = NewEncoder(encoding, len(literals))
+		e.AtMost(literals, 10)
+		if len(e.Clauses()) > 5000 {
+			t.Errorf("%s: expected at most 5000 clauses for k = 10, but got %d",
+				encoding, len(e.Clauses()))
+		}
+	}
+}
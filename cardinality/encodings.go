@@ -0,0 +1,262 @@
+package cardinality
+
+import (
+	"github.com/jvoung/s1t"
+)
+
+// The encodings of at-most-k, for 0 < k < len(literals).
+
+const none = -1
+
+// maxCommanderK is the largest k for the commander encoding, whose groups need
+// C(2k+2, k+1) clauses: 20 for k = 2, but about 700k for k = 10.
+const maxCommanderK = 2
+
+func (e *Encoder) pairwise(literals []s1t.Literal, k int) {
+	// Each subset of k+1 literals, chosen by increasing indices.
+	indices := make([]int, k+1)
+	for i := range indices {
+		indices[i] = i
+	}
+	for {
+		clause := make([]s1t.Literal, len(indices))
+		for i, index := range indices {
+			clause[i] = literals[index].Negate()
+		}
+		e.AddClause(clause...)
+		i := len(indices) - 1
+		for i >= 0 && indices[i] == len(literals)-len(indices)+i {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		indices[i]++
+		for j := i + 1; j < len(indices); j++ {
+			indices[j] = indices[j-1] + 1
+		}
+	}
+}
+
+func (e *Encoder) sequentialCounter(literals []s1t.Literal, k int) {
+	// counts[j] is true if more than j of the literals so far are true.
+	counts := make([]s1t.Literal, k)
+	for i, l := range literals {
+		if i > 0 {
+			// The last literal can't exceed the count, but needs no register.
+			e.AddClause(l.Negate(), counts[k-1].Negate())
+		}
+		if i == len(literals)-1 {
+			return
+		}
+		next := make([]s1t.Literal, k)
+		for j := range next {
+			next[j] = e.newVar()
+			if j == 0 {
+				e.AddClause(l.Negate(), next[j])
+			}
+			if i > 0 {
+				e.AddClause(counts[j].Negate(), next[j])
+				if j > 0 {
+					e.AddClause(l.Negate(), counts[j-1].Negate(), next[j])
+				}
+			} else if j > 0 {
+				e.AddClause(next[j].Negate())
+			}
+		}
+		counts = next
+	}
+}
+
+func (e *Encoder) commander(literals []s1t.Literal, k int) {
+	if k > maxCommanderK {
+		e.sequentialCounter(literals, k)
+		return
+	}
+	if len(literals) <= k+2 {
+		e.pairwise(literals, k)
+		return
+	}
+	// Each group has as many commanders as its count of true literals, up to
+	// k. The commanders are ordered, so the first ones are the true ones.
+	var commanders []s1t.Literal
+	for start := 0; start < len(literals); start += k + 2 {
+		group := literals[start:min(start+k+2, len(literals))]
+		if len(group) <= k {
+			commanders = append(commanders, group...)
+			continue
+		}
+		members := append([]s1t.Literal{}, group...)
+		for j := 0; j < k; j++ {
+			c := e.newVar()
+			if j > 0 {
+				e.AddClause(c.Negate(), commanders[len(commanders)-1])
+			}
+			commanders = append(commanders, c)
+			members = append(members, c.Negate())
+		}
+		// Exactly k of the group and the negated commanders are true: the true
+		// literals and the true commanders are as many.
+		e.pairwise(members, k)
+		negated := make([]s1t.Literal, len(members))
+		for i, l := range members {
+			negated[i] = l.Negate()
+		}
+		e.pairwise(negated, len(members)-k)
+	}
+	e.AtMost(commanders, k)
+}
+
+func (e *Encoder) totalizer(literals []s1t.Literal, k int) {
+	outputs := e.count(literals, k+1)
+	e.AddClause(outputs[k].Negate())
+}
+
+// count returns up to limit outputs of a totalizer, where outputs[j] is true
+// if more than j of the literals are true.
+func (e *Encoder) count(literals []s1t.Literal, limit int) []s1t.Literal {
+	if len(literals) == 1 {
+		return literals
+	}
+	left := e.count(literals[:len(literals)/2], limit)
+	right := e.count(literals[len(literals)/2:], limit)
+	outputs := make([]s1t.Literal, min(len(literals), limit))
+	for i := range outputs {
+		outputs[i] = e.newVar()
+	}
+	for i := 0; i <= len(left); i++ {
+		for j := 0; j <= len(right); j++ {
+			if i+j == 0 {
+				continue
+			}
+			clause := []s1t.Literal{outputs[min(i+j, limit)-1]}
+			if i > 0 {
+				clause = append(clause, left[i-1].Negate())
+			}
+			if j > 0 {
+				clause = append(clause, right[j-1].Negate())
+			}
+			e.AddClause(clause...)
+		}
+	}
+	return outputs
+}
+
+func (e *Encoder) network(literals []s1t.Literal, k int) {
+	// The k+1 largest of blocks of m literals, padded with false (none).
+	m := 1
+	for m <= k {
+		m <<= 1
+	}
+	wires := make([]int, (len(literals)+m-1)/m*m)
+	for i := range wires {
+		wires[i] = none
+		if i < len(literals) {
+			wires[i] = int(literals[i])
+		}
+	}
+	if largest := e.card(wires, m); largest[k] != none {
+		e.AddClause(s1t.Literal(largest[k]).Negate())
+	}
+}
+
+// card returns the m largest wires, sorted from true to false, for a number
+// of wires that is a multiple of m.
+func (e *Encoder) card(wires []int, m int) []int {
+	if len(wires) == m {
+		return e.sort(wires)
+	}
+	return e.simplifiedMerge(e.card(wires[:m], m), e.card(wires[m:], m))[:m]
+}
+
+// sort sorts a power of 2 wires by odd-even merges.
+func (e *Encoder) sort(wires []int) []int {
+	if len(wires) == 1 {
+		return wires
+	}
+	half := len(wires) / 2
+	return e.merge(e.sort(wires[:half]), e.sort(wires[half:]))
+}
+
+// merge merges two sorted lists of wires of the same power of 2 length.
+func (e *Encoder) merge(a, b []int) []int {
+	if len(a) == 1 {
+		high, low := e.comparator(a[0], b[0])
+		return []int{high, low}
+	}
+	odd := e.merge(everyOther(a, 0), everyOther(b, 0))
+	even := e.merge(everyOther(a, 1), everyOther(b, 1))
+	merged := []int{odd[0]}
+	for i := 0; i < len(a)-1; i++ {
+		high, low := e.comparator(odd[i+1], even[i])
+		merged = append(merged, high, low)
+	}
+	return append(merged, even[len(even)-1])
+}
+
+// simplifiedMerge is like merge, but only returns the len(a)+1 largest wires.
+func (e *Encoder) simplifiedMerge(a, b []int) []int {
+	if len(a) == 1 {
+		return e.merge(a, b)
+	}
+	odd := e.simplifiedMerge(everyOther(a, 0), everyOther(b, 0))
+	even := e.simplifiedMerge(everyOther(a, 1), everyOther(b, 1))
+	merged := []int{odd[0]}
+	for i := 0; i < len(a)/2; i++ {
+		high, low := e.comparator(odd[i+1], even[i])
+		merged = append(merged, high, low)
+	}
+	return merged
+}
+
+func everyOther(wires []int, start int) []int {
+	var result []int
+	for i := start; i < len(wires); i += 2 {
+		result = append(result, wires[i])
+	}
+	return result
+}
+
+// comparator returns wires for the max and the min of two wires. Its clauses
+// only make them true when they must be, which is enough for at-most.
+func (e *Encoder) comparator(a, b int) (int, int) {
+	if a == none {
+		return b, none
+	}
+	if b == none {
+		return a, none
+	}
+	x, y := s1t.Literal(a), s1t.Literal(b)
+	high, low := e.newVar(), e.newVar()
+	e.AddClause(x.Negate(), high)
+	e.AddClause(y.Negate(), high)
+	e.AddClause(x.Negate(), y.Negate(), low)
+	return int(high), int(low)
+}
+
+func (e *Encoder) ladder(literals []s1t.Literal) {
+	// ladder[i] is true if one of the literals after i is true. A true literal
+	// i needs ladder[i-1] and not ladder[i].
+	ladder := make([]s1t.Literal, len(literals)-1)
+	for i := range ladder {
+		ladder[i] = e.newVar()
+		if i > 0 {
+			e.AddClause(ladder[i].Negate(), ladder[i-1])
+		}
+	}
+	for i, l := range literals {
+		if i > 0 {
+			e.AddClause(l.Negate(), ladder[i-1])
+		}
+		if i < len(ladder) {
+			e.AddClause(l.Negate(), ladder[i].Negate())
+		}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,57 @@
+// The check-proof subcommand: verifies a proof that a CNF problem is UNSAT.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jvoung/s1t"
+	"github.com/jvoung/s1t/proofcheck"
+)
+
+// checkProof runs "s1t check-proof [-lrat] problem.cnf proof" and returns the
+// exit code: 0 if the proof is verified, 1 if not, 2 on bad usage.
+func checkProof(args []string) int {
+	flags := flag.NewFlagSet("check-proof", flag.ExitOnError)
+	lrat := flags.Bool("lrat", false, "the proof is in LRAT format, instead of DRAT")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: s1t check-proof [-lrat] problem.cnf proof\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	input, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Error opening problem: %v\n", err)
+		return 2
+	}
+	defer input.Close()
+	problem, err := s1t.ParseDimacs(input)
+	if err != nil {
+		fmt.Printf("Error parsing input %v: %v\n", flags.Arg(0), err)
+		return 2
+	}
+	proof, err := os.Open(flags.Arg(1))
+	if err != nil {
+		fmt.Printf("Error opening proof: %v\n", err)
+		return 2
+	}
+	defer proof.Close()
+	if *lrat {
+		err = proofcheck.CheckLRAT(problem, proof)
+	} else {
+		err = proofcheck.CheckDRAT(problem, proof)
+	}
+	if err != nil {
+		fmt.Printf("c %v\n", err)
+		fmt.Println("s NOT VERIFIED")
+		return 1
+	}
+	fmt.Println("s VERIFIED")
+	return 0
+}
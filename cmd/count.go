@@ -0,0 +1,53 @@
+// The count subcommand: counts the models of a CNF problem.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jvoung/s1t"
+)
+
+// countModels runs "s1t count [problem.cnf]" and returns the exit code: 0 on
+// success, 2 on bad usage or input. Reads stdin if there is no file. If the
+// problem has "c ind" lines, it counts the models projected onto those vars.
+// Cardinality and xor constraints count like clauses.
+func countModels(args []string) int {
+	flags := flag.NewFlagSet("count", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: s1t count [problem.cnf]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	input := os.Stdin
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		var err error
+		input, err = os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Printf("Error opening problem: %v\n", err)
+			return 2
+		}
+		defer input.Close()
+	default:
+		flags.Usage()
+		return 2
+	}
+	startTime := time.Now()
+	problem, err := s1t.ParseDimacs(input)
+	if err != nil {
+		fmt.Printf("Error parsing input: %v\n", err)
+		return 2
+	}
+	if len(problem.Independent) > 0 {
+		fmt.Printf("c Projecting onto %d vars\n", len(problem.Independent))
+	}
+	count := s1t.Count(problem)
+	fmt.Printf("c Counted in %v\n", time.Since(startTime))
+	fmt.Printf("s mc %v\n", count)
+	return 0
+}
@@ -3,9 +3,11 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"runtime/pprof"
 	"time"
 
@@ -13,8 +15,54 @@ import (
 )
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
+var heuristic = flag.String("heuristic", "evsids", "decision heuristic: evsids, vsids or ordered")
+var restart = flag.String("restart", "luby",
+	"restart policy: luby, glucose, geometric, fixed or none")
+var seed = flag.Int64("seed", 0, "if non-zero, randomizes the initial variable order")
+var maxConflicts = flag.Int("max-conflicts", 0, "give up after this many conflicts (0 is unlimited)")
+var maxDecisions = flag.Int("max-decisions", 0, "give up after this many decisions (0 is unlimited)")
+var maxPropagations = flag.Int("max-propagations", 0,
+	"give up after this many propagations (0 is unlimited)")
+var timeLimit = flag.Duration("time-limit", 0, "give up after this long (0 is unlimited)")
+var verbosity = flag.Int("verbosity", 0, "0 (quiet), 1 (log restarts) or 2 (log more)")
+var proof = flag.String("proof", "", "write a DRAT proof to file (checkable if UNSAT)")
+var binaryProof = flag.Bool("binary-proof", false, "write the -proof in binary DRAT format")
+var coreFile = flag.String("core", "", "if UNSAT, write an unsat core to file in DIMACS format")
+var minimizeCore = flag.Bool("minimize-core", false,
+	"minimize the -core to a minimal unsatisfiable subset")
+var recoverXors = flag.Bool("recover-xors", false,
+	"find xor clauses encoded by the clauses, and propagate them by Gaussian elimination")
+var preprocess = flag.Bool("preprocess", false,
+	"simplify the clauses first by subsumption, variable elimination, equivalent literals and blocked clauses")
+var dumpPreprocessed = flag.String("dump-preprocessed", "",
+	"write the -preprocess simplified clauses to file in DIMACS format")
+var allModels = flag.Bool("all-models", false, "print every model, instead of one")
+var maxModels = flag.Int("max-models", 0, "print up to this many models (0 is one, or all with -all-models)")
+
+var inputFormat = flag.String("format", "dimacs",
+	"input format: dimacs, wcnf for weighted dimacs which may lack hard clauses, formula for infix formulas like (a | ~b) & c, or opb for pseudo-Boolean problems")
+
+var heuristics = map[string]s1t.Heuristic{
+	"evsids":  s1t.EVSIDS,
+	"vsids":   s1t.VSIDS,
+	"ordered": s1t.Ordered,
+}
+
+var restartPolicies = map[string]s1t.RestartPolicy{
+	"luby":      s1t.LubyRestarts,
+	"glucose":   s1t.GlucoseRestarts,
+	"geometric": s1t.GeometricRestarts,
+	"fixed":     s1t.FixedRestarts,
+	"none":      s1t.NoRestarts,
+}
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "check-proof" {
+		os.Exit(checkProof(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "count" {
+		os.Exit(countModels(os.Args[2:]))
+	}
 	startTime := time.Now()
 	flag.Parse()
 	remaining := flag.Args()
@@ -35,11 +83,22 @@ func main() {
 			len(remaining))
 		os.Exit(1)
 	}
+	opts := parseOptions()
+	if *proof != "" {
+		proofFile, err := os.Create(*proof)
+		if err != nil {
+			fmt.Printf("Error creating proof file: %v\n", err)
+			os.Exit(1)
+		}
+		defer proofFile.Close()
+		opts.Proof = proofFile
+		opts.BinaryProof = *binaryProof
+	}
 	if *cpuprofile != "" {
 		enableCPUProfile(*cpuprofile)
 		defer pprof.StopCPUProfile()
 	}
-	problem, err := s1t.ParseDimacs(input)
+	problem, err := parseInput(input)
 	if err != nil {
 		fmt.Printf("Error parsing input %v: %e\n", input, err)
 		os.Exit(1)
@@ -47,13 +106,172 @@ func main() {
 	fmt.Printf("c Processing %d vars, %d clauses (parsed input in %f s)\n",
 		problem.Spec.NumVariables, problem.Spec.NumClauses,
 		time.Since(startTime).Seconds())
-	solution := s1t.Solve(problem)
-	fmt.Print(solution.Output(problem))
+	// Interrupting gives up, but still prints an (UNKNOWN) answer.
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		cancel()
+	}()
+	solver := s1t.NewSolver(problem, opts)
+	if *dumpPreprocessed != "" {
+		writePreprocessed(*dumpPreprocessed, solver)
+	}
+	if problem.Spec.Format == "wcnf" {
+		solveMaxSat(ctx, solver, problem)
+	} else if len(problem.Objective) > 0 {
+		minimize(ctx, solver, problem)
+	} else if *allModels || *maxModels > 0 {
+		enumerateModels(ctx, solver, problem)
+	} else {
+		solve(ctx, solver, problem)
+	}
 	fmt.Printf("t %s %d %d %f\n",
 		problem.Spec.Format, problem.Spec.NumVariables, problem.Spec.NumVariables,
 		time.Since(startTime).Seconds())
 }
 
+func solve(ctx context.Context, solver *s1t.Solver, problem s1t.Problem) {
+	solution, err := solver.SolveContext(ctx)
+	stats := solver.Stats()
+	fmt.Print(stats.Output())
+	if err != nil {
+		fmt.Printf("c %v\n", err)
+	}
+	if *coreFile != "" && solution.Status == s1t.Unsat {
+		writeCore(*coreFile, problem, solution.Core)
+	}
+	fmt.Print(solution.Output(problem))
+}
+
+// solveMaxSat prints an "o" line with the cost of each better solution as it
+// is found, then the best solution.
+func solveMaxSat(ctx context.Context, solver *s1t.Solver, problem s1t.Problem) {
+	solution, err := solver.SolveMaxSatContext(ctx, problem.Soft, func(solution s1t.Solution) {
+		fmt.Printf("o %d\n", solution.Cost(problem))
+	})
+	stats := solver.Stats()
+	fmt.Print(stats.Output())
+	if err != nil {
+		fmt.Printf("c %v\n", err)
+	} else if solution.Status == s1t.Sat {
+		fmt.Println("c Optimum found")
+	}
+	fmt.Print(solution.Output(problem))
+}
+
+// minimize prints an "o" line with the objective of each better solution as
+// it is found, then the best solution.
+func minimize(ctx context.Context, solver *s1t.Solver, problem s1t.Problem) {
+	solution, err := solver.MinimizeContext(ctx, problem.Objective, func(solution s1t.Solution) {
+		fmt.Printf("o %d\n", solution.Objective(problem))
+	})
+	stats := solver.Stats()
+	fmt.Print(stats.Output())
+	if err != nil {
+		fmt.Printf("c %v\n", err)
+	} else if solution.Status == s1t.Sat {
+		fmt.Println("c Optimum found")
+	}
+	fmt.Print(solution.Output(problem))
+}
+
+// enumerateModels prints each model as it is found, or UNSAT if there are none.
+func enumerateModels(ctx context.Context, solver *s1t.Solver, problem s1t.Problem) {
+	count, err := solver.EnumerateContext(ctx, nil, *maxModels, func(solution s1t.Solution) bool {
+		fmt.Print(solution.Output(problem))
+		return true
+	})
+	stats := solver.Stats()
+	fmt.Print(stats.Output())
+	if err != nil {
+		fmt.Printf("c %v\n", err)
+	}
+	fmt.Printf("c Found %d models\n", count)
+	if count == 0 && err == nil {
+		unsat := s1t.Solution{Status: s1t.Unsat}
+		fmt.Print(unsat.Output(problem))
+	}
+}
+
+func parseInput(input *os.File) (s1t.Problem, error) {
+	switch *inputFormat {
+	case "dimacs":
+		return s1t.ParseDimacs(input)
+	case "wcnf":
+		return s1t.ParseWCNF(input)
+	case "formula":
+		return s1t.ParseFormula(input)
+	case "opb":
+		return s1t.ParseOPB(input)
+	}
+	return s1t.Problem{}, fmt.Errorf("Unknown input format %q", *inputFormat)
+}
+
+func parseOptions() s1t.Options {
+	h, ok := heuristics[*heuristic]
+	if !ok {
+		fmt.Printf("Unknown heuristic %q\n", *heuristic)
+		os.Exit(1)
+	}
+	r, ok := restartPolicies[*restart]
+	if !ok {
+		fmt.Printf("Unknown restart policy %q\n", *restart)
+		os.Exit(1)
+	}
+	return s1t.Options{
+		Heuristic:       h,
+		Restart:         r,
+		Seed:            *seed,
+		MaxConflicts:    *maxConflicts,
+		MaxDecisions:    *maxDecisions,
+		MaxPropagations: *maxPropagations,
+		TimeLimit:       *timeLimit,
+		Verbosity:       *verbosity,
+		Log:             os.Stdout,
+		Core:            *coreFile != "",
+		MinimizeCore:    *coreFile != "" && *minimizeCore,
+		RecoverXors:     *recoverXors,
+		Preprocess:      *preprocess || *dumpPreprocessed != "",
+	}
+}
+
+func writeCore(path string, problem s1t.Problem, core []s1t.ClauseNum) {
+	coreProblem := s1t.Problem{Spec: problem.Spec}
+	for _, cnum := range core {
+		coreProblem.Clauses = append(coreProblem.Clauses, problem.Clauses[cnum])
+	}
+	f, err := os.Create(path)
+	if err == nil {
+		err = s1t.WriteDimacs(f, coreProblem)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Printf("c Error writing core: %v\n", err)
+		return
+	}
+	fmt.Printf("c Wrote a core of %d clauses to %s\n", len(core), path)
+}
+
+func writePreprocessed(path string, solver *s1t.Solver) {
+	simplified := solver.Simplified()
+	f, err := os.Create(path)
+	if err == nil {
+		err = s1t.WriteDimacs(f, simplified)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Printf("c Error writing preprocessed clauses: %v\n", err)
+		return
+	}
+	fmt.Printf("c Wrote %d preprocessed clauses to %s\n", len(simplified.Clauses), path)
+}
+
 func enableCPUProfile(cpuprofile string) {
 	f, err := os.Create(cpuprofile)
 	if err != nil {
@@ -0,0 +1,195 @@
+// Unsat cores: the input clauses that the final conflict was derived from.
+
+package s1t
+
+import (
+	"sort"
+)
+
+// coreTracker records what each learned clause and each assignment at level 0
+// was derived from, as a graph of nodes. Input clauses are the leaves.
+type coreTracker struct {
+	parents [][]int // nodes that each node was derived from
+	input   []int   // input index of each node, or none if derived
+
+	clauseNode   []int       // node of each clause (slots get new nodes if reused)
+	unitNode     []int       // node of each var assigned at level 0, or none
+	inputClauses []ClauseNum // clause of each input index
+	pending      []int       // nodes used by the current conflict analysis
+}
+
+func newCoreTracker(numVars int) *coreTracker {
+	c := &coreTracker{}
+	for v := 0; v < numVars; v++ {
+		c.newVar()
+	}
+	return c
+}
+
+func (c *coreTracker) newVar() {
+	c.unitNode = append(c.unitNode, none)
+}
+
+func (c *coreTracker) newNode(input int, parents []int) int {
+	c.parents = append(c.parents, parents)
+	c.input = append(c.input, input)
+	return len(c.parents) - 1
+}
+
+// newClause makes room for a clause slot.
+func (c *coreTracker) newClause() {
+	c.clauseNode = append(c.clauseNode, none)
+}
+
+// addInput records an input clause, the next in order.
+func (c *coreTracker) addInput(cnum ClauseNum) {
+	c.clauseNode[cnum] = c.newNode(len(c.inputClauses), nil)
+	c.inputClauses = append(c.inputClauses, cnum)
+}
+
+// use records a clause as an antecedent of the clause being learned.
+func (c *coreTracker) use(cnum ClauseNum) {
+	c.pending = append(c.pending, c.clauseNode[cnum])
+}
+
+// useUnit records a var assigned at level 0 as an antecedent.
+func (c *coreTracker) useUnit(v VarNum) {
+	c.pending = append(c.pending, c.unitNode[v])
+}
+
+// learned records the pending antecedents as those of a learned clause.
+func (c *coreTracker) learned(cnum ClauseNum) {
+	c.clauseNode[cnum] = c.newNode(none, c.pending)
+	c.pending = nil
+}
+
+// antecedents returns the nodes for a clause and the level 0 assignments of
+// its literals.
+func (c *coreTracker) antecedents(cl Clause, cnum ClauseNum) []int {
+	parents := []int{c.clauseNode[cnum]}
+	for _, l := range cl.Literals {
+		if n := c.unitNode[l.Var()]; n != none {
+			parents = append(parents, n)
+		}
+	}
+	return parents
+}
+
+// fixed records that v was assigned at level 0 by clause cnum, whose other
+// literals are false at level 0.
+func (c *coreTracker) fixed(v VarNum, cl Clause, cnum ClauseNum) {
+	c.unitNode[v] = c.newNode(none, c.antecedents(cl, cnum))
+}
+
+// core returns the input indices that the conflict was derived from, given
+// that every literal of the conflict clause is false at level 0.
+func (c *coreTracker) core(cl Clause, cnum ClauseNum) []ClauseNum {
+	visited := make([]bool, len(c.parents))
+	queue := c.antecedents(cl, cnum)
+	var core []ClauseNum
+	for len(queue) > 0 {
+		n := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if visited[n] {
+			continue
+		}
+		visited[n] = true
+		if c.input[n] != none {
+			core = append(core, ClauseNum(c.input[n]))
+		}
+		queue = append(queue, c.parents[n]...)
+	}
+	sort.Slice(core, func(i, j int) bool { return core[i] < core[j] })
+	return core
+}
+
+// setUnsat records that the problem is unsat, since the conflict clause is
+// false at level 0, and concludes the proof and the core if needed.
+func (s *Solver) setUnsat(conflict ClauseNum) {
+	s.ok = false
+	if s.proof != nil {
+		s.proof.addEmpty()
+	}
+	if s.core != nil {
+		s.unsatCore = s.core.core(s.clauses[conflict], conflict)
+	}
+}
+
+// inputProblem returns the input clauses, in the order of their indices.
+func (s *Solver) inputProblem() Problem {
+	p := Problem{Spec: ProblemSpec{Format: "cnf", NumVariables: s.NumVars()}}
+	for _, cnum := range s.core.inputClauses {
+		p.Clauses = append(p.Clauses, s.clauses[cnum])
+	}
+	p.Spec.NumClauses = len(p.Clauses)
+	return p
+}
+
+// MinimizeCore shrinks an unsat core of problem to a minimal unsatisfiable
+// subset: removing any of the returned clauses makes the rest satisfiable.
+// It tries removing each clause in turn (solving with opts, without a proof),
+// and on unsat keeps only the clauses that the new conflict needed.
+// On error (e.g. an exhausted budget), it returns a core which is unsat but
+// maybe not minimal.
+func MinimizeCore(problem Problem, core []ClauseNum, opts Options) ([]ClauseNum, error) {
+	// Clause i of the core is enabled by assuming selector numVars + i.
+	numVars := problem.Spec.NumVariables
+	selectors := Problem{Spec: ProblemSpec{
+		Format:       "cnf",
+		NumVariables: numVars + len(core),
+		NumClauses:   len(core),
+	}}
+	selectorOf := make(map[Literal]int)
+	for i, cnum := range core {
+		selector := Positive(VarNum(numVars + i))
+		selectorOf[selector] = i
+		literals := append([]Literal{selector.Negate()}, problem.Clauses[cnum].Literals...)
+		selectors.Clauses = append(selectors.Clauses, Clause{Literals: literals})
+	}
+	opts.Proof = nil
+	opts.Tracer = nil
+	opts.Core = false
+	opts.MinimizeCore = false
+	s := NewSolver(selectors, opts)
+
+	enabled := make([]bool, len(core))
+	for i := range enabled {
+		enabled[i] = true
+	}
+	for i := range core {
+		if !enabled[i] {
+			continue
+		}
+		var assumptions []Literal
+		for j := range core {
+			if j != i && enabled[j] {
+				assumptions = append(assumptions, Positive(VarNum(numVars+j)))
+			}
+		}
+		solution, err := s.SolveAssuming(assumptions)
+		if err != nil {
+			return selectedCore(core, enabled), err
+		}
+		if solution.Status == Sat {
+			continue
+		}
+		// Only keep the clauses that are needed without clause i.
+		for j := range enabled {
+			enabled[j] = false
+		}
+		for _, l := range solution.Failed {
+			enabled[selectorOf[l]] = true
+		}
+	}
+	return selectedCore(core, enabled), nil
+}
+
+func selectedCore(core []ClauseNum, enabled []bool) []ClauseNum {
+	var result []ClauseNum
+	for i, cnum := range core {
+		if enabled[i] {
+			result = append(result, cnum)
+		}
+	}
+	return result
+}
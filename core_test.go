@@ -0,0 +1,108 @@
+package s1t
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// checkCoreIsUnsat checks that the core clauses of problem are unsat by themselves.
+func checkCoreIsUnsat(t *testing.T, problem Problem, core []ClauseNum) {
+	sub := Problem{Spec: problem.Spec}
+	for _, cnum := range core {
+		sub.Clauses = append(sub.Clauses, problem.Clauses[cnum])
+	}
+	sub.Spec.NumClauses = len(sub.Clauses)
+	if solution := Solve(sub); solution.Status != Unsat {
+		t.Errorf("Expected core %v to be unsat, but got %v", core, solution)
+	}
+}
+
+func TestCore(t *testing.T) {
+	cases := []struct {
+		lines    []string
+		expected []ClauseNum
+	}{
+		{[]string{"p cnf 2 3", "1 2 0", "-1 0", "-2 0"}, []ClauseNum{0, 1, 2}},
+		{[]string{"p cnf 3 4", "1 2 0", "1 0", "3 0", "-1 0"}, []ClauseNum{1, 3}},
+		{[]string{"p cnf 3 3", "1 2 0", "0", "3 0"}, []ClauseNum{1}},
+		{[]string{"p cnf 4 6", "3 4 0", "1 2 0", "-1 2 0", "-3 0", "1 -2 0", "-1 -2 0"},
+			[]ClauseNum{1, 2, 4, 5}},
+	}
+	for _, c := range cases {
+		problem := inputToProblem(c.lines, t)
+		solution, _ := NewSolver(problem, Options{Core: true}).Solve()
+		if solution.Status != Unsat || !cmp.Equal(solution.Core, c.expected) {
+			t.Errorf("Problem %v, expected unsat with core %v, but got %v",
+				c.lines, c.expected, solution)
+		}
+	}
+}
+
+func TestCoreOfPigeonHole(t *testing.T) {
+	lines := pigeonHoleLines(5)
+	// Satisfiable extra clauses, which the core doesn't need.
+	numPigeonHole := len(lines) - 1
+	lines = append(lines, "31 32 0", "-31 32 0", "31 -32 0")
+	lines[0] = fmt.Sprintf("p cnf 32 %d", len(lines)-1)
+	problem := inputToProblem(lines, t)
+	for _, opts := range []Options{
+		{Core: true},
+		{Core: true, Restart: NoRestarts, ReduceInterval: 20},
+		{MinimizeCore: true},
+	} {
+		solution, _ := NewSolver(problem, opts).Solve()
+		if solution.Status != Unsat || len(solution.Core) == 0 {
+			t.Fatalf("Expected unsat with a core, but got %v", solution)
+		}
+		checkCoreIsUnsat(t, problem, solution.Core)
+		if opts.MinimizeCore {
+			// The pigeon hole clauses are a minimal unsatisfiable subset.
+			var expected []ClauseNum
+			for i := 0; i < numPigeonHole; i++ {
+				expected = append(expected, ClauseNum(i))
+			}
+			if !cmp.Equal(solution.Core, expected) {
+				t.Errorf("Expected the minimal core %v, but got %v", expected, solution.Core)
+			}
+		}
+	}
+}
+
+func TestMinimizeCore(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 3 7",
+		"1 2 0", "-1 2 0", "1 -2 0", "-1 -2 0",
+		"3 0", "-3 0", "1 3 0",
+	}, t)
+	core := []ClauseNum{0, 1, 2, 3, 4, 5, 6}
+	minimal, err := MinimizeCore(problem, core, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Either of the two minimal subsets.
+	if !cmp.Equal(minimal, []ClauseNum{0, 1, 2, 3}) && !cmp.Equal(minimal, []ClauseNum{4, 5}) {
+		t.Errorf("Expected a minimal core, but got %v", minimal)
+	}
+}
+
+func TestCoreWithAddClause(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 2 2",
+		"1 2 0",
+		"-1 2 0",
+	}, t)
+	s := NewSolver(problem, Options{Core: true})
+	if solution, _ := s.Solve(); solution.Status != Sat {
+		t.Fatalf("Expected sat, but got %v", solution)
+	}
+	s.AddClause(Positive(0))
+	s.AddClause(Negative(1), Positive(0))
+	s.AddClause(Negative(1), Negative(0))
+	solution, _ := s.Solve()
+	expected := []ClauseNum{1, 2, 4}
+	if solution.Status != Unsat || !cmp.Equal(solution.Core, expected) {
+		t.Errorf("Expected unsat with core %v, but got %v", expected, solution)
+	}
+}
@@ -0,0 +1,323 @@
+// Exact model counting (#SAT) by DPLL search with component decomposition and
+// component caching.
+
+package s1t
+
+import (
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+// Count returns the number of models of problem. If problem.Independent lists
+// vars, it instead counts the assignments of those vars that extend to a model
+// (projected model counting). Cardinality, pseudo-Boolean and xor constraints
+// are encoded to clauses over new vars, which are not counted.
+func Count(problem Problem) *big.Int {
+	c := counter{cache: make(map[string]*big.Int)}
+	clauses, numVars := constraintClauses(problem)
+	independent := problem.Independent
+	if len(independent) == 0 && numVars > problem.Spec.NumVariables {
+		for v := 0; v < problem.Spec.NumVariables; v++ {
+			independent = append(independent, VarNum(v))
+		}
+	}
+	if len(independent) > 0 {
+		c.projected = make([]bool, numVars)
+		for _, v := range independent {
+			c.projected[v] = true
+		}
+	}
+	vars := make([]VarNum, numVars)
+	for v := range vars {
+		vars[v] = VarNum(v)
+	}
+	return c.count(clauses, vars)
+}
+
+// constraintClauses returns problem's clauses, followed by clauses for its
+// other constraints which use new vars, and the number of vars. The
+// assignments of problem's vars which satisfy the constraints are those
+// which extend to a model of the clauses.
+func constraintClauses(problem Problem) ([]Clause, int) {
+	clauses := append([]Clause{}, problem.Clauses...)
+	numVars := problem.Spec.NumVariables
+	newVar := func() Literal {
+		numVars++
+		return Positive(VarNum(numVars - 1))
+	}
+	for _, a := range problem.AtMosts {
+		clauses = append(clauses, pbClauses(a.constraint(), newVar)...)
+	}
+	for _, c := range problem.Constraints {
+		clauses = append(clauses, pbClauses(c, newVar)...)
+	}
+	for _, x := range problem.Xors {
+		clauses = append(clauses, xorClauses(x, newVar)...)
+	}
+	return clauses, numVars
+}
+
+// pbClauses encodes a pseudo-Boolean constraint like a decision diagram: a
+// new var for each term i and part r of the bound implies that the terms from
+// i on sum to at least r.
+func pbClauses(c PBConstraint, newVar func() Literal) []Clause {
+	terms, bound := normalize(c)
+	if bound <= 0 {
+		return nil
+	}
+	// rest[i] is the sum of the coefficients of the terms from i on.
+	rest := make([]int64, len(terms)+1)
+	for i := len(terms) - 1; i >= 0; i-- {
+		rest[i] = rest[i+1] + terms[i].Coefficient
+	}
+	var clauses []Clause
+	nodes := make(map[[2]int64]Literal)
+	var node func(i int, r int64) Literal
+	node = func(i int, r int64) Literal {
+		key := [2]int64{int64(i), r}
+		if n, ok := nodes[key]; ok {
+			return n
+		}
+		n := newVar()
+		nodes[key] = n
+		if rest[i] < r {
+			clauses = append(clauses, Clause{Literals: []Literal{n.Negate()}})
+			return n
+		}
+		t := terms[i]
+		// Without term i, the rest needs r, and with it, r minus its
+		// coefficient.
+		clauses = append(clauses, Clause{Literals: []Literal{n.Negate(), t.Literal, node(i+1, r)}})
+		if r > t.Coefficient {
+			clauses = append(clauses, Clause{Literals: []Literal{n.Negate(), node(i+1, r-t.Coefficient)}})
+		}
+		return n
+	}
+	root := node(0, bound)
+	return append(clauses, Clause{Literals: []Literal{root}})
+}
+
+// xorClauses encodes an xor clause as a chain of new vars, each the xor of
+// the previous one and a literal.
+func xorClauses(x XorClause, newVar func() Literal) []Clause {
+	if len(x.Literals) == 0 {
+		return []Clause{{}}
+	}
+	sum := x.Literals[0]
+	var clauses []Clause
+	for _, l := range x.Literals[1:] {
+		// next = sum xor l
+		next := newVar()
+		clauses = append(clauses,
+			Clause{Literals: []Literal{next.Negate(), sum, l}},
+			Clause{Literals: []Literal{next.Negate(), sum.Negate(), l.Negate()}},
+			Clause{Literals: []Literal{next, sum.Negate(), l}},
+			Clause{Literals: []Literal{next, sum, l.Negate()}})
+		sum = next
+	}
+	return append(clauses, Clause{Literals: []Literal{sum}})
+}
+
+// counter holds the state of one call to Count.
+type counter struct {
+	projected []bool // vars to count, or nil to count all vars
+	cache     map[string]*big.Int
+}
+
+func (c *counter) isCounted(v VarNum) bool {
+	return c.projected == nil || c.projected[v]
+}
+
+// count returns the number of assignments to vars (restricted to the counted
+// ones) which satisfy clauses. Every var of the clauses must be in vars.
+func (c *counter) count(clauses []Clause, vars []VarNum) *big.Int {
+	clauses, assigned, ok := unitPropagate(clauses)
+	if !ok {
+		return big.NewInt(0)
+	}
+	// Vars that are neither assigned nor in a clause are free.
+	inClause := make(map[VarNum]bool)
+	for _, clause := range clauses {
+		for _, l := range clause.Literals {
+			inClause[l.Var()] = true
+		}
+	}
+	free := uint(0)
+	for _, v := range vars {
+		if !assigned[v] && !inClause[v] && c.isCounted(v) {
+			free++
+		}
+	}
+	result := new(big.Int).Lsh(big.NewInt(1), free)
+	for _, component := range components(clauses) {
+		n := c.countComponent(component)
+		if n.Sign() == 0 {
+			return n
+		}
+		result.Mul(result, n)
+	}
+	return result
+}
+
+// countComponent counts the models of connected clauses, by branching on the
+// var with the most occurrences. When no counted vars are left, it only checks
+// whether there is a model.
+func (c *counter) countComponent(clauses []Clause) *big.Int {
+	key := componentKey(clauses)
+	if n, ok := c.cache[key]; ok {
+		return n
+	}
+	occurrences := make(map[VarNum]int)
+	var vars []VarNum
+	for _, clause := range clauses {
+		for _, l := range clause.Literals {
+			v := l.Var()
+			if occurrences[v] == 0 {
+				vars = append(vars, v)
+			}
+			occurrences[v]++
+		}
+	}
+	branch := vars[0]
+	for _, v := range vars[1:] {
+		if c.isCounted(v) != c.isCounted(branch) {
+			if c.isCounted(v) {
+				branch = v
+			}
+		} else if occurrences[v] > occurrences[branch] {
+			branch = v
+		}
+	}
+	counted := c.isCounted(branch)
+	others := make([]VarNum, 0, len(vars)-1)
+	for _, v := range vars {
+		if v != branch {
+			others = append(others, v)
+		}
+	}
+	n := c.count(assume(clauses, Positive(branch)), others)
+	if counted {
+		n = new(big.Int).Add(n, c.count(assume(clauses, Negative(branch)), others))
+	} else if n.Sign() == 0 {
+		// There are no counted vars left, so n is 0 or 1.
+		n = c.count(assume(clauses, Negative(branch)), others)
+	}
+	c.cache[key] = n
+	return n
+}
+
+// assume returns the clauses simplified by l being true: without the clauses
+// that contain l, and without ¬l in the others.
+func assume(clauses []Clause, l Literal) []Clause {
+	result := make([]Clause, 0, len(clauses))
+	for _, clause := range clauses {
+		satisfied := false
+		removed := none
+		for i, other := range clause.Literals {
+			if other == l {
+				satisfied = true
+				break
+			}
+			if other == l.Negate() {
+				removed = i
+			}
+		}
+		switch {
+		case satisfied:
+		case removed == none:
+			result = append(result, clause)
+		default:
+			literals := make([]Literal, 0, len(clause.Literals)-1)
+			literals = append(literals, clause.Literals[:removed]...)
+			literals = append(literals, clause.Literals[removed+1:]...)
+			result = append(result, Clause{Literals: literals})
+		}
+	}
+	return result
+}
+
+// unitPropagate assigns the literals of unit clauses until there are none.
+// Returns the simplified clauses and the assigned vars, or false if some
+// clause became empty.
+func unitPropagate(clauses []Clause) ([]Clause, map[VarNum]bool, bool) {
+	assigned := make(map[VarNum]bool)
+	for {
+		unit := none
+		for _, clause := range clauses {
+			if clause.Empty() {
+				return nil, nil, false
+			}
+			if len(clause.Literals) == 1 {
+				unit = int(clause.Literals[0])
+				break
+			}
+		}
+		if unit == none {
+			return clauses, assigned, true
+		}
+		l := Literal(unit)
+		assigned[l.Var()] = true
+		clauses = assume(clauses, l)
+	}
+}
+
+// components splits the clauses into groups that share no vars.
+func components(clauses []Clause) [][]Clause {
+	// Union-find over the vars.
+	parent := make(map[VarNum]VarNum)
+	var find func(v VarNum) VarNum
+	find = func(v VarNum) VarNum {
+		p, ok := parent[v]
+		if !ok || p == v {
+			return v
+		}
+		root := find(p)
+		parent[v] = root
+		return root
+	}
+	for _, clause := range clauses {
+		first := find(clause.Literals[0].Var())
+		for _, l := range clause.Literals[1:] {
+			if root := find(l.Var()); root != first {
+				parent[root] = first
+			}
+		}
+	}
+	index := make(map[VarNum]int)
+	var result [][]Clause
+	for _, clause := range clauses {
+		root := find(clause.Literals[0].Var())
+		i, ok := index[root]
+		if !ok {
+			i = len(result)
+			index[root] = i
+			result = append(result, nil)
+		}
+		result[i] = append(result[i], clause)
+	}
+	return result
+}
+
+// componentKey identifies a set of clauses regardless of the order of the
+// clauses and of their literals.
+func componentKey(clauses []Clause) string {
+	keys := make([]string, len(clauses))
+	for i, clause := range clauses {
+		literals := append([]Literal{}, clause.Literals...)
+		sort.Slice(literals, func(a, b int) bool { return literals[a] < literals[b] })
+		var key []byte
+		for _, l := range literals {
+			key = strconv.AppendInt(key, int64(l), 10)
+			key = append(key, ' ')
+		}
+		keys[i] = string(key)
+	}
+	sort.Strings(keys)
+	var key []byte
+	for _, k := range keys {
+		key = append(key, k...)
+		key = append(key, ',')
+	}
+	return string(key)
+}
@@ -0,0 +1,130 @@
+package s1t
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// randomProblem returns a random 3-CNF problem.
+func randomProblem(rng *rand.Rand, numVars, numClauses int) Problem {
+	problem := Problem{Spec: ProblemSpec{Format: "cnf", NumVariables: numVars, NumClauses: numClauses}}
+	for i := 0; i < numClauses; i++ {
+		var c Clause
+		for _, v := range rng.Perm(numVars)[:3] {
+			if rng.Intn(2) == 0 {
+				c.Literals = append(c.Literals, Positive(VarNum(v)))
+			} else {
+				c.Literals = append(c.Literals, Negative(VarNum(v)))
+			}
+		}
+		problem.Clauses = append(problem.Clauses, c)
+	}
+	return problem
+}
+
+func TestCount(t *testing.T) {
+	cases := []struct {
+		lines    []string
+		expected int64
+	}{
+		{[]string{"p cnf 3 0"}, 8},
+		{[]string{"p cnf 3 1", "1 2 3 0"}, 7},
+		{[]string{"p cnf 5 1", "1 2 3 0"}, 28},
+		{[]string{"p cnf 2 2", "1 0", "-1 0"}, 0},
+		{[]string{"p cnf 4 2", "1 2 0", "3 4 0"}, 9},
+		{[]string{"p cnf 3 2", "1 2 0", "-2 3 0"}, 4},
+		// Projected onto vars 1 and 2.
+		{[]string{"c ind 1 2 0", "p cnf 3 1", "1 2 3 0"}, 4},
+		{[]string{"p cnf 3 2", "1 2 3 0", "-1 -2 0", "c ind 1 0"}, 2},
+		{[]string{"c ind 3 0", "p cnf 3 2", "1 0", "-1 2 0"}, 2},
+		{[]string{"c ind 3 0", "p cnf 3 3", "1 0", "-1 2 0", "-2 -1 0"}, 0},
+		// Constraints other than clauses.
+		{[]string{"p cnf 2 1", "k 1 1 2 0"}, 3},
+		{[]string{"p cnf 2 1", "x1 2 0"}, 2},
+		{[]string{"p cnf 4 2", "k 2 1 2 3 4 0", "x-1 2 3 0"}, 5},
+		{[]string{"c ind 1 0", "p cnf 3 2", "x1 2 3 0", "k 0 2 3 0"}, 1},
+	}
+	for _, c := range cases {
+		n := Count(inputToProblem(c.lines, t))
+		if n.Cmp(big.NewInt(c.expected)) != 0 {
+			t.Errorf("Problem %v, expected %d models but got %v", c.lines, c.expected, n)
+		}
+	}
+}
+
+func TestCountMatchesEnumerate(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		problem := randomProblem(rng, 12, 20+rng.Intn(30))
+		var projection []VarNum
+		if i%2 == 1 {
+			for _, v := range rng.Perm(12)[:5] {
+				projection = append(projection, VarNum(v))
+			}
+			problem.Independent = projection
+		}
+		enumerated, err := NewSolver(problem, Options{}).Enumerate(projection, 0,
+			func(Solution) bool { return true })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := Count(problem); n.Cmp(big.NewInt(int64(enumerated))) != 0 {
+			t.Errorf("Problem %d, projection %v: enumerated %d models, but counted %v",
+				i, projection, enumerated, n)
+		}
+	}
+}
+
+func TestCountConstraints(t *testing.T) {
+	rng := rand.New(rand.NewSource(15))
+	for i := 0; i < 100; i++ {
+		problem := randomPBProblem(rng, 7, rng.Intn(6), 1+rng.Intn(3))
+		for j := rng.Intn(3); j > 0; j-- {
+			var x XorClause
+			for _, v := range rng.Perm(7)[:1+rng.Intn(4)] {
+				x.Literals = append(x.Literals, Positive(VarNum(v)))
+			}
+			problem.Xors = append(problem.Xors, x)
+		}
+		expected := 0
+		for bits := 0; bits < 1<<7; bits++ {
+			solution := Solution{Status: Sat, IsSat: true, Assignment: make([]int, 7)}
+			for v := range solution.Assignment {
+				solution.Assignment[v] = (bits >> uint(v)) & 1
+			}
+			ok, _ := solution.Satisfies(problem)
+			okConstraints, _ := solution.SatisfiesConstraints(problem)
+			okXors, _ := solution.SatisfiesXors(problem)
+			if ok && okConstraints && okXors {
+				expected++
+			}
+		}
+		if n := Count(problem); n.Cmp(big.NewInt(int64(expected))) != 0 {
+			t.Errorf("Expected %d models, but got %v for %+v", expected, n, problem)
+		}
+	}
+}
+
+func TestCountLarge(t *testing.T) {
+	// Independent pairs of vars with 3 models each.
+	lines := []string{"p cnf 200 100"}
+	for v := 1; v < 200; v += 2 {
+		lines = append(lines, fmt.Sprintf("%d %d 0", v, v+1))
+	}
+	expected := new(big.Int).Exp(big.NewInt(3), big.NewInt(100), nil)
+	if n := Count(inputToProblem(lines, t)); n.Cmp(expected) != 0 {
+		t.Errorf("Expected 3^100 models, but got %v", n)
+	}
+
+	input, err := os.Open("test_cnf/queen4.cnf")
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	defer input.Close()
+	if n := Count(parseOrDie(input, t)); n.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("Expected 2 models of queen4, but got %v", n)
+	}
+}
@@ -8,20 +8,97 @@ import (
 	"strings"
 )
 
-// ParseDimacs parses input in DIMACS format.
+// ParseDimacs parses input in DIMACS format: CNF, or weighted CNF for MaxSAT
+// either with a "p wcnf" spec line (with an optional top weight) or in the 2022
+// format with "h" for hard clauses and no spec line, or a "sat" formula which
+// it transforms to clauses. Input without a spec line is only taken to be in
+// the 2022 format if it has an "h" line. CNF may also have xor clauses on
+// lines starting with "x", like "x1 -2 3 0", and cardinality constraints on
+// lines starting with "k" and the bound, like "k 2 1 -2 3 0" for at most 2 of
+// the literals, or in the MiniCard style, like "1 -2 3 <= 2" (or ">=" for at
+// least). These count as clauses in the spec line.
 func ParseDimacs(in io.Reader) (Problem, error) {
+	return parseDimacs(in, false)
+}
+
+// ParseWCNF parses weighted CNF like ParseDimacs, but also takes input without
+// a spec line or "h" lines, which has only soft clauses, to be in the 2022
+// format.
+func ParseWCNF(in io.Reader) (Problem, error) {
+	return parseDimacs(in, true)
+}
+
+func parseDimacs(in io.Reader, wcnf bool) (Problem, error) {
 	s := bufio.NewScanner(in)
 	var spec ProblemSpec
 	var clauses []Clause
 	prevClause := newClause()
 	prevLiterals := make(map[Literal]bool)
+	var independent []VarNum
+	var soft []SoftClause
+	var xors []XorClause
+	var atMosts []AtMost
+	inferVars := false
+	// Weighted clauses before the first "h" line, when there is no spec line.
+	var pending []string
+	var formula strings.Builder
 	for s.Scan() {
 		line := s.Text()
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == "c" {
+			if len(fields) > 1 && fields[1] == "ind" {
+				vars, err := parseIndependent(fields[2:])
+				if err != nil {
+					return Problem{}, err
+				}
+				independent = append(independent, vars...)
+			}
+			continue
+		}
+		if spec.Format == "" && len(fields) > 0 && isWeight(fields[0]) {
+			if fields[0] != "h" && !wcnf {
+				pending = append(pending, line)
+				continue
+			}
+			// The 2022 WCNF format has no spec line, and marks hard clauses by
+			// "h" instead of a top weight.
+			spec.Format = "wcnf"
+			inferVars = true
+			for _, p := range pending {
+				err := parseWcnfClause(p, &spec, inferVars, &clauses, &soft)
+				if err != nil {
+					return Problem{}, err
+				}
+			}
+			pending = nil
+		}
+		if len(pending) > 0 && len(fields) > 0 && spec.Format == "" {
+			// Not the 2022 format, so the first line lacks a spec.
+			return Problem{}, parseSpec(pending[0], &spec)
+		}
 		if spec.Format == "" {
 			err := parseSpec(line, &spec)
 			if err != nil {
 				return Problem{}, err
 			}
+		} else if isSatFormat(spec.Format) {
+			formula.WriteString(line)
+			formula.WriteString("\n")
+		} else if spec.Format == "wcnf" {
+			err := parseWcnfClause(line, &spec, inferVars, &clauses, &soft)
+			if err != nil {
+				return Problem{}, err
+			}
+		} else if len(fields) > 0 && strings.HasPrefix(fields[0], "x") {
+			err := parseXorClause(line, spec, &xors)
+			if err != nil {
+				return Problem{}, err
+			}
+		} else if isCardinalityLine(fields) {
+			err := parseAtMost(line, spec, &atMosts)
+			if err != nil {
+				return Problem{}, err
+			}
 		} else {
 			err := parseCnfClause(line, spec, &prevClause, &clauses, &prevLiterals)
 			if err != nil {
@@ -29,15 +106,52 @@ func ParseDimacs(in io.Reader) (Problem, error) {
 			}
 		}
 	}
+	if len(pending) > 0 {
+		return Problem{}, parseSpec(pending[0], &spec)
+	}
+	if isSatFormat(spec.Format) {
+		var err error
+		clauses, err = parseSatFormula(formula.String(), &spec)
+		if err != nil {
+			return Problem{}, err
+		}
+	}
 	// 0 terminator is not required for the last clause, so just add if there.
 	if !prevClause.Empty() {
 		clauses = append(clauses, prevClause)
 	}
-	if len(clauses) != spec.NumClauses {
+	if inferVars {
+		spec.NumClauses = len(clauses) + len(soft)
+	}
+	numClauses := len(clauses) + len(soft) + len(xors) + len(atMosts)
+	if numClauses != spec.NumClauses {
 		return Problem{}, fmt.Errorf("Expected %d clauses, but got %d",
-			spec.NumClauses, len(clauses))
+			spec.NumClauses, numClauses)
 	}
-	return Problem{Spec: spec, Clauses: clauses}, nil
+	for _, v := range independent {
+		if int(v) >= spec.NumVariables {
+			return Problem{}, fmt.Errorf("Independent variable %d goes beyond pre-declared num vars %d",
+				v+1, spec.NumVariables)
+		}
+	}
+	return Problem{Spec: spec, Clauses: clauses, Soft: soft, AtMosts: atMosts, Xors: xors,
+		Independent: independent}, nil
+}
+
+// parseIndependent parses the vars of a "c ind" line, terminated by 0.
+func parseIndependent(fields []string) ([]VarNum, error) {
+	var vars []VarNum
+	for _, f := range fields {
+		num, err := strconv.Atoi(f)
+		if err != nil || num < 0 {
+			return nil, fmt.Errorf("Failed to parse var %q in \"c ind\" line", f)
+		}
+		if num == clauseTerminatorNum {
+			break
+		}
+		vars = append(vars, VarNum(num-1))
+	}
+	return vars, nil
 }
 
 func parseSpec(line string, spec *ProblemSpec) error {
@@ -52,13 +166,20 @@ func parseSpec(line string, spec *ProblemSpec) error {
 		return fmt.Errorf("Spec line starts with unknown char %q: %q",
 			fields[0], line)
 	}
-	if len(fields) != 4 {
-		return fmt.Errorf("Expected 4 fields of spec but got %d fields: %q",
-			len(fields), line)
+	// Formulas have no number of clauses, and wcnf may have a top weight.
+	numFields := 4
+	if len(fields) > 1 && isSatFormat(fields[1]) {
+		numFields = 3
+	} else if len(fields) > 1 && fields[1] == "wcnf" && len(fields) == 5 {
+		numFields = 5
+	}
+	if len(fields) != numFields {
+		return fmt.Errorf("Expected %d fields of spec but got %d fields: %q",
+			numFields, len(fields), line)
 	}
 	fileFormat := fields[1]
-	if !strings.Contains(fileFormat, "cnf") {
-		return fmt.Errorf("Expected \"cnf\" format but got %q: %q",
+	if !strings.Contains(fileFormat, "cnf") && !isSatFormat(fileFormat) {
+		return fmt.Errorf("Expected \"cnf\" or \"sat\" format but got %q: %q",
 			fileFormat, line)
 	}
 	vars, err := strconv.Atoi(fields[2])
@@ -66,15 +187,27 @@ func parseSpec(line string, spec *ProblemSpec) error {
 		return fmt.Errorf("Expected non-negative integer for number of vars: %q, %e",
 			fields[2], err)
 	}
-	clauses, err := strconv.Atoi(fields[3])
-	if err != nil || clauses < 0 {
-		return fmt.Errorf("Expected non-negative integer for number of clauses: %q, %e",
-			fields[3], err)
+	clauses := 0
+	if numFields > 3 {
+		clauses, err = strconv.Atoi(fields[3])
+		if err != nil || clauses < 0 {
+			return fmt.Errorf("Expected non-negative integer for number of clauses: %q, %e",
+				fields[3], err)
+		}
+	}
+	var top uint64
+	if numFields == 5 {
+		top, err = strconv.ParseUint(fields[4], 10, 64)
+		if err != nil || top == 0 {
+			return fmt.Errorf("Expected positive integer for top weight: %q, %e",
+				fields[4], err)
+		}
 	}
 	*spec = ProblemSpec{
 		Format:       fileFormat,
 		NumVariables: vars,
 		NumClauses:   clauses,
+		TopWeight:    top,
 	}
 	return nil
 }
@@ -100,13 +233,7 @@ func parseCnfClause(line string, spec ProblemSpec, prevClause *Clause,
 			return fmt.Errorf("Variable number %d goes beyond pre-declared num vars %d",
 				intAbs(num), spec.NumVariables)
 		}
-		var literal Literal
-		// shift numbering back to 0
-		if num < 0 {
-			literal = Negative(VarNum(-num - 1))
-		} else {
-			literal = Positive(VarNum(num - 1))
-		}
+		literal := FromDimacs(num)
 		_, hadLiteral := (*prevLiterals)[literal]
 		if !hadLiteral {
 			prevClause.Literals = append(prevClause.Literals, literal)
@@ -116,6 +243,150 @@ func parseCnfClause(line string, spec ProblemSpec, prevClause *Clause,
 	return nil
 }
 
+// parseXorClause parses a line of an xor clause: "x" then the literals,
+// terminated by 0.
+func parseXorClause(line string, spec ProblemSpec, xors *[]XorClause) error {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), "x"))
+	if len(fields) == 0 || fields[len(fields)-1] != "0" {
+		return fmt.Errorf("Expected xor clause to end with 0: %q", line)
+	}
+	var x XorClause
+	for _, f := range fields[:len(fields)-1] {
+		num, err := strconv.Atoi(f)
+		if err != nil {
+			return fmt.Errorf("Failed to parse var %q in xor clause %q %e",
+				f, line, err)
+		}
+		if num == clauseTerminatorNum {
+			return fmt.Errorf("Expected one xor clause per line: %q", line)
+		}
+		if intAbs(num) > spec.NumVariables {
+			return fmt.Errorf("Variable number %d goes beyond pre-declared num vars %d",
+				intAbs(num), spec.NumVariables)
+		}
+		x.Literals = append(x.Literals, FromDimacs(num))
+	}
+	*xors = append(*xors, x)
+	return nil
+}
+
+func isCardinalityLine(fields []string) bool {
+	if len(fields) > 0 && fields[0] == "k" {
+		return true
+	}
+	return len(fields) >= 2 && (fields[len(fields)-2] == "<=" || fields[len(fields)-2] == ">=")
+}
+
+// parseAtMost parses a line of a cardinality constraint: "k", the bound, then
+// the literals terminated by 0, or the literals, "<=" or ">=", then the bound.
+func parseAtMost(line string, spec ProblemSpec, atMosts *[]AtMost) error {
+	fields := strings.Fields(line)
+	var bound string
+	var literals []string
+	atLeast := false
+	if fields[0] == "k" {
+		if len(fields) < 3 || fields[len(fields)-1] != "0" {
+			return fmt.Errorf("Expected \"k\", a bound and literals ending with 0: %q", line)
+		}
+		bound, literals = fields[1], fields[2:len(fields)-1]
+	} else {
+		bound, literals = fields[len(fields)-1], fields[:len(fields)-2]
+		atLeast = fields[len(fields)-2] == ">="
+	}
+	k, err := strconv.Atoi(bound)
+	if err != nil {
+		return fmt.Errorf("Failed to parse bound %q in cardinality constraint %q %e",
+			bound, line, err)
+	}
+	a := AtMost{K: k}
+	for _, f := range literals {
+		num, err := strconv.Atoi(f)
+		if err != nil || num == clauseTerminatorNum {
+			return fmt.Errorf("Failed to parse var %q in cardinality constraint %q",
+				f, line)
+		}
+		if intAbs(num) > spec.NumVariables {
+			return fmt.Errorf("Variable number %d goes beyond pre-declared num vars %d",
+				intAbs(num), spec.NumVariables)
+		}
+		a.Literals = append(a.Literals, FromDimacs(num))
+	}
+	if atLeast {
+		// At least k are true if at most len - k are false.
+		for i, l := range a.Literals {
+			a.Literals[i] = l.Negate()
+		}
+		a.K = len(a.Literals) - k
+	}
+	*atMosts = append(*atMosts, a)
+	return nil
+}
+
+// isWeight returns true if the field starts a clause of the 2022 WCNF format.
+func isWeight(field string) bool {
+	if field == "h" {
+		return true
+	}
+	_, err := strconv.ParseUint(field, 10, 64)
+	return err == nil
+}
+
+// parseWcnfClause parses a line with a weight (or "h" for hard), then the
+// literals of a clause terminated by 0. Clauses at the spec's top weight are
+// hard. If inferVars, the vars are not pre-declared, and the spec's number
+// of vars grows to fit.
+func parseWcnfClause(line string, spec *ProblemSpec, inferVars bool,
+	hard *[]Clause, soft *[]SoftClause) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	isHard := fields[0] == "h"
+	var weight uint64
+	if !isHard {
+		var err error
+		weight, err = strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("Failed to parse weight %q in clause %q %e",
+				fields[0], line, err)
+		}
+		isHard = spec.TopWeight > 0 && weight >= spec.TopWeight
+	}
+	if fields[len(fields)-1] != "0" {
+		return fmt.Errorf("Expected weighted clause to end with 0: %q", line)
+	}
+	clause := newClause()
+	seen := make(map[Literal]bool)
+	for _, f := range fields[1 : len(fields)-1] {
+		num, err := strconv.Atoi(f)
+		if err != nil {
+			return fmt.Errorf("Failed to parse var %q in clause %q %e",
+				f, line, err)
+		}
+		if num == clauseTerminatorNum {
+			return fmt.Errorf("Expected one weighted clause per line: %q", line)
+		}
+		if intAbs(num) > spec.NumVariables {
+			if !inferVars {
+				return fmt.Errorf("Variable number %d goes beyond pre-declared num vars %d",
+					intAbs(num), spec.NumVariables)
+			}
+			spec.NumVariables = intAbs(num)
+		}
+		literal := FromDimacs(num)
+		if !seen[literal] {
+			clause.Literals = append(clause.Literals, literal)
+			seen[literal] = true
+		}
+	}
+	if isHard {
+		*hard = append(*hard, clause)
+	} else {
+		*soft = append(*soft, SoftClause{Clause: clause, Weight: weight})
+	}
+	return nil
+}
+
 func newClause() Clause {
 	return Clause{
 		Literals: []Literal{},
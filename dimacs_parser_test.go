@@ -54,6 +54,11 @@ func TestPreambleErrorCases(t *testing.T) {
 				"p cnf 3 1",
 			},
 		},
+		{
+			desc:                 "Missing spec line",
+			lines:                []string{"1 2 0", "-1 0"},
+			expectedErrSubstring: "Spec line starts with unknown char",
+		},
 		{
 			desc:  "Truncated cnf spec",
 			lines: []string{"p cnf 0"},
@@ -236,3 +241,246 @@ func TestCnfErrorCases(t *testing.T) {
 		}
 	}
 }
+
+func TestIndependentVars(t *testing.T) {
+	problem, err := ParseDimacs(strings.NewReader(strings.Join([]string{
+		"c ind 1 3 0",
+		"p cnf 4 1",
+		"1 -2 0",
+		"c ind 4 0",
+	}, "\n")))
+	if err != nil {
+		t.Fatalf("Expected no errors but got %v", err)
+	}
+	expected := []VarNum{0, 2, 3}
+	if !cmp.Equal(problem.Independent, expected) {
+		t.Errorf("Expected independent vars %v, but got %v", expected, problem.Independent)
+	}
+
+	_, err = ParseDimacs(strings.NewReader(strings.Join([]string{
+		"c ind 5 0",
+		"p cnf 4 0",
+	}, "\n")))
+	if err == nil || !strings.Contains(err.Error(), "Independent variable 5 goes beyond") {
+		t.Errorf("Expected an error for var 5, but got %v", err)
+	}
+}
+
+func TestWcnf(t *testing.T) {
+	cases := []struct {
+		desc         string
+		lines        []string
+		expectedHard []Clause
+		expectedSoft []SoftClause
+	}{
+		{
+			desc:         "Classic format with top weight",
+			lines:        []string{"p wcnf 2 3 9", "9 1 -2 0", "3 2 0", "c comment", "12 -1 0"},
+			expectedHard: []Clause{{Literals: []Literal{Positive(0), Negative(1)}}, {Literals: []Literal{Negative(0)}}},
+			expectedSoft: []SoftClause{{Clause: Clause{Literals: []Literal{Positive(1)}}, Weight: 3}},
+		},
+		{
+			desc:  "Classic format without top weight",
+			lines: []string{"p wcnf 2 2", "1 1 -2 0", "4 2 0"},
+			expectedSoft: []SoftClause{
+				{Clause: Clause{Literals: []Literal{Positive(0), Negative(1)}}, Weight: 1},
+				{Clause: Clause{Literals: []Literal{Positive(1)}}, Weight: 4},
+			},
+		},
+		{
+			desc:         "2022 format",
+			lines:        []string{"c comment", "h 1 -3 0", "5 2 0", "h 2 0"},
+			expectedHard: []Clause{{Literals: []Literal{Positive(0), Negative(2)}}, {Literals: []Literal{Positive(1)}}},
+			expectedSoft: []SoftClause{{Clause: Clause{Literals: []Literal{Positive(1)}}, Weight: 5}},
+		},
+	}
+	for _, c := range cases {
+		problem, err := ParseDimacs(strings.NewReader(strings.Join(c.lines, "\n")))
+		if err != nil {
+			t.Errorf("Case %q, expected no errors but got %v", c.desc, err)
+			continue
+		}
+		if problem.Spec.Format != "wcnf" {
+			t.Errorf("Case %q, expected wcnf format but got %q", c.desc, problem.Spec.Format)
+		}
+		if !equalClauses(problem.Clauses, c.expectedHard) || !cmp.Equal(problem.Soft, c.expectedSoft) {
+			t.Errorf("Case %q, expected hard clauses %v and soft clauses %v, but got %v and %v",
+				c.desc, c.expectedHard, c.expectedSoft, problem.Clauses, problem.Soft)
+		}
+	}
+	problem := inputToProblem([]string{"c comment", "h 1 -3 0", "5 2 0", "h 2 0"}, t)
+	if problem.Spec.NumVariables != 3 || problem.Spec.NumClauses != 3 {
+		t.Errorf("Expected 3 vars and 3 clauses, but got %v", problem.Spec)
+	}
+	// Soft clauses before the first "h" line.
+	problem = inputToProblem([]string{"5 2 0", "", "3 -1 0", "h 1 -3 0"}, t)
+	if len(problem.Clauses) != 1 || len(problem.Soft) != 2 || problem.Spec.NumVariables != 3 {
+		t.Errorf("Expected 1 hard and 2 soft clauses over 3 vars, but got %v", problem)
+	}
+	// Only soft clauses, which need the format to be explicit.
+	problem, err := ParseWCNF(strings.NewReader("5 2 0\n3 -1 0\n"))
+	if err != nil || len(problem.Soft) != 2 || problem.Spec.NumClauses != 2 {
+		t.Errorf("Expected 2 soft clauses, but got %v, %v", problem, err)
+	}
+}
+
+func TestWcnfErrorCases(t *testing.T) {
+	cases := []errorTestCase{
+		{
+			desc:                 "Variable number greater than predeclared",
+			lines:                []string{"p wcnf 1 1 5", "2 1 -2 0"},
+			expectedErrSubstring: "Variable number 2 goes beyond pre-declared",
+		},
+		{
+			desc:                 "Missing terminator",
+			lines:                []string{"h 1 2"},
+			expectedErrSubstring: "Expected weighted clause to end with 0",
+		},
+		{
+			desc:                 "Bad weight",
+			lines:                []string{"p wcnf 2 1", "x 1 2 0"},
+			expectedErrSubstring: "Failed to parse weight",
+		},
+		{
+			desc:                 "Clauses fewer than predeclared",
+			lines:                []string{"p wcnf 2 3 4", "4 1 0", "1 2 0"},
+			expectedErrSubstring: "Expected 3 clauses, but got 2",
+		},
+	}
+	for _, c := range cases {
+		_, err := ParseDimacs(strings.NewReader(strings.Join(c.lines, "\n")))
+		if err == nil || !strings.Contains(err.Error(), c.expectedErrSubstring) {
+			t.Errorf("case %q, expected err string %q but got %v",
+				c.desc, c.expectedErrSubstring, err)
+		}
+	}
+}
+
+func TestWriteDimacsRoundTrip(t *testing.T) {
+	input := []string{
+		"p cnf 4 3",
+		"1 -2 0",
+		"-4 0",
+		"2 3 -1 0",
+	}
+	problem := inputToProblem(input, t)
+	var b strings.Builder
+	if err := WriteDimacs(&b, problem); err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join(input, "\n") + "\n"
+	if b.String() != expected {
+		t.Errorf("Expected output %q, but got %q", expected, b.String())
+	}
+}
+
+func TestXorLines(t *testing.T) {
+	input := []string{
+		"p cnf 3 3",
+		"1 -2 0",
+		"x1 -2 3 0",
+		"x -3 0",
+	}
+	problem := inputToProblem(input, t)
+	expected := []XorClause{
+		{Literals: []Literal{Positive(0), Negative(1), Positive(2)}},
+		{Literals: []Literal{Negative(2)}},
+	}
+	if !cmp.Equal(problem.Xors, expected) || len(problem.Clauses) != 1 {
+		t.Errorf("Expected xor clauses %v and 1 clause, but got %v and %v",
+			expected, problem.Xors, problem.Clauses)
+	}
+	var b strings.Builder
+	if err := WriteDimacs(&b, problem); err != nil {
+		t.Fatal(err)
+	}
+	written := strings.Join([]string{"p cnf 3 3", "1 -2 0", "x1 -2 3 0", "x-3 0"}, "\n") + "\n"
+	if b.String() != written {
+		t.Errorf("Expected output %q, but got %q", written, b.String())
+	}
+	if again := inputToProblem(strings.Split(b.String(), "\n"), t); !cmp.Equal(again.Xors, expected) {
+		t.Errorf("Expected xor clauses %v after writing, but got %v", expected, again.Xors)
+	}
+
+	cases := []errorTestCase{
+		{
+			desc:                 "Missing terminator",
+			lines:                []string{"p cnf 2 1", "x1 2"},
+			expectedErrSubstring: "Expected xor clause to end with 0",
+		},
+		{
+			desc:                 "Two xor clauses on a line",
+			lines:                []string{"p cnf 2 2", "x1 0 2 0"},
+			expectedErrSubstring: "Expected one xor clause per line",
+		},
+		{
+			desc:                 "Variable number greater than predeclared",
+			lines:                []string{"p cnf 2 1", "x1 3 0"},
+			expectedErrSubstring: "Variable number 3 goes beyond pre-declared",
+		},
+	}
+	for _, c := range cases {
+		_, err := ParseDimacs(strings.NewReader(strings.Join(c.lines, "\n")))
+		if err == nil || !strings.Contains(err.Error(), c.expectedErrSubstring) {
+			t.Errorf("case %q, expected err string %q but got %v",
+				c.desc, c.expectedErrSubstring, err)
+		}
+	}
+}
+
+func TestCardinalityLines(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 3 4",
+		"1 -2 0",
+		"k 2 1 -2 3 0",
+		"1 2 <= 1",
+		"-1 2 3 >= 2",
+	}, t)
+	expected := []AtMost{
+		{Literals: []Literal{Positive(0), Negative(1), Positive(2)}, K: 2},
+		{Literals: []Literal{Positive(0), Positive(1)}, K: 1},
+		{Literals: []Literal{Positive(0), Negative(1), Negative(2)}, K: 1},
+	}
+	if !cmp.Equal(problem.AtMosts, expected) || len(problem.Clauses) != 1 {
+		t.Errorf("Expected cardinality constraints %v and 1 clause, but got %v and %v",
+			expected, problem.AtMosts, problem.Clauses)
+	}
+	var b strings.Builder
+	if err := WriteDimacs(&b, problem); err != nil {
+		t.Fatal(err)
+	}
+	if again := inputToProblem(strings.Split(b.String(), "\n"), t); !cmp.Equal(again.AtMosts, expected) {
+		t.Errorf("Expected cardinality constraints %v after writing %q, but got %v",
+			expected, b.String(), again.AtMosts)
+	}
+
+	cases := []errorTestCase{
+		{
+			desc:                 "Missing terminator",
+			lines:                []string{"p cnf 2 1", "k 1 1 2"},
+			expectedErrSubstring: "Expected \"k\", a bound and literals ending with 0",
+		},
+		{
+			desc:                 "Bad bound",
+			lines:                []string{"p cnf 2 1", "1 2 <= x"},
+			expectedErrSubstring: "Failed to parse bound",
+		},
+		{
+			desc:                 "Terminator before the bound",
+			lines:                []string{"p cnf 2 1", "1 2 0 <= 1"},
+			expectedErrSubstring: "Failed to parse var",
+		},
+		{
+			desc:                 "Variable number greater than predeclared",
+			lines:                []string{"p cnf 2 1", "k 1 1 3 0"},
+			expectedErrSubstring: "Variable number 3 goes beyond pre-declared",
+		},
+	}
+	for _, c := range cases {
+		_, err := ParseDimacs(strings.NewReader(strings.Join(c.lines, "\n")))
+		if err == nil || !strings.Contains(err.Error(), c.expectedErrSubstring) {
+			t.Errorf("case %q, expected err string %q but got %v",
+				c.desc, c.expectedErrSubstring, err)
+		}
+	}
+}
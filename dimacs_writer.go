@@ -0,0 +1,50 @@
+package s1t
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// WriteDimacs writes the problem in DIMACS CNF format, with its cardinality
+// constraints on lines starting with "k" and its xor clauses on lines starting
+// with "x".
+func WriteDimacs(w io.Writer, problem Problem) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("p cnf ")
+	b.WriteString(strconv.Itoa(problem.Spec.NumVariables))
+	b.WriteString(" ")
+	b.WriteString(strconv.Itoa(len(problem.Clauses) + len(problem.AtMosts) + len(problem.Xors)))
+	b.WriteString("\n")
+	var buf []byte
+	for _, c := range problem.Clauses {
+		buf = buf[:0]
+		for _, l := range c.Literals {
+			buf = strconv.AppendInt(buf, int64(l.Dimacs()), 10)
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, "0\n"...)
+		b.Write(buf)
+	}
+	for _, a := range problem.AtMosts {
+		buf = append(buf[:0], "k "...)
+		buf = strconv.AppendInt(buf, int64(a.K), 10)
+		buf = append(buf, ' ')
+		for _, l := range a.Literals {
+			buf = strconv.AppendInt(buf, int64(l.Dimacs()), 10)
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, "0\n"...)
+		b.Write(buf)
+	}
+	for _, x := range problem.Xors {
+		buf = append(buf[:0], 'x')
+		for _, l := range x.Literals {
+			buf = strconv.AppendInt(buf, int64(l.Dimacs()), 10)
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, "0\n"...)
+		b.Write(buf)
+	}
+	return b.Flush()
+}
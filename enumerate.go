@@ -0,0 +1,68 @@
+// Model enumeration, by blocking each model found and solving again.
+
+package s1t
+
+import (
+	"context"
+	"fmt"
+)
+
+// Enumerate calls callback with each model of problem, until the callback
+// returns false or limit models were found (if limit is positive). Returns the
+// number of models found.
+func Enumerate(problem Problem, limit int, callback func(Solution) bool) (int, error) {
+	return NewSolver(problem, Options{}).Enumerate(nil, limit, callback)
+}
+
+// Enumerate is like the Enumerate function, but projected onto the given vars
+// (or all vars if there are none): models which only differ on other vars are
+// enumerated once. It adds a clause to block each model it finds, so later
+// calls to Solve only find models not enumerated yet.
+func (s *Solver) Enumerate(projection []VarNum, limit int, callback func(Solution) bool) (int, error) {
+	return s.EnumerateContext(context.Background(), projection, limit, callback)
+}
+
+// EnumerateContext is like Enumerate, but also gives up with the context's
+// error if ctx is done first.
+func (s *Solver) EnumerateContext(ctx context.Context, projection []VarNum, limit int,
+	callback func(Solution) bool) (int, error) {
+	for _, v := range projection {
+		if int(v) >= s.NumVars() {
+			return 0, fmt.Errorf("Projected variable %v goes beyond the %d vars", v, s.NumVars())
+		}
+	}
+	if len(projection) == 0 {
+		for v := 0; v < s.NumVars(); v++ {
+			projection = append(projection, VarNum(v))
+		}
+	}
+	count := 0
+	for limit <= 0 || count < limit {
+		solution, err := s.SolveContext(ctx)
+		if err != nil || solution.Status != Sat {
+			return count, err
+		}
+		count++
+		if !callback(solution) {
+			break
+		}
+		if err := s.AddClause(blockingClause(solution, projection)...); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// blockingClause returns a clause which is false for the solution's values of
+// the given vars.
+func blockingClause(solution Solution, vars []VarNum) []Literal {
+	block := make([]Literal, len(vars))
+	for i, v := range vars {
+		if solution.Assignment[v] == 1 {
+			block[i] = Negative(v)
+		} else {
+			block[i] = Positive(v)
+		}
+	}
+	return block
+}
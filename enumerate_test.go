@@ -0,0 +1,96 @@
+package s1t
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestEnumerate(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 3 1",
+		"1 2 3 0",
+	}, t)
+	cases := []struct {
+		limit    int
+		stopAt   int // callback returns false on this model
+		expected int
+	}{
+		{0, 0, 7},
+		{3, 0, 3},
+		{7, 0, 7},
+		{100, 0, 7},
+		{0, 2, 2},
+	}
+	for _, c := range cases {
+		seen := make(map[string]bool)
+		count, err := Enumerate(problem, c.limit, func(solution Solution) bool {
+			if ok, _ := solution.Satisfies(problem); !ok {
+				t.Errorf("Solution %v doesn't satisfy the problem", solution)
+			}
+			key := fmt.Sprint(solution.Assignment)
+			if seen[key] {
+				t.Errorf("Model %v enumerated twice", solution)
+			}
+			seen[key] = true
+			return len(seen) != c.stopAt
+		})
+		if err != nil || count != c.expected || len(seen) != c.expected {
+			t.Errorf("Limit %d, expected %d models, but got %d (%d seen), %v",
+				c.limit, c.expected, count, len(seen), err)
+		}
+	}
+}
+
+func TestEnumerateProjected(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 3 2",
+		"1 2 3 0",
+		"-1 -2 0",
+	}, t)
+	s := NewSolver(problem, Options{})
+	seen := make(map[int]bool)
+	count, err := s.Enumerate([]VarNum{1}, 0, func(solution Solution) bool {
+		seen[solution.Assignment[1]] = true
+		return true
+	})
+	if err != nil || count != 2 || len(seen) != 2 {
+		t.Errorf("Expected 2 projected models, but got %d, %v (%v)", count, err, seen)
+	}
+	if _, err := s.Enumerate([]VarNum{3}, 0, func(Solution) bool { return true }); err == nil {
+		t.Error("Expected an error for an undeclared variable")
+	}
+}
+
+func TestEnumerate4Queens(t *testing.T) {
+	input, err := os.Open("test_cnf/queen4.cnf")
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	defer input.Close()
+	problem := parseOrDie(input, t)
+	var solutions []Solution
+	count, err := Enumerate(problem, 0, func(solution Solution) bool {
+		solutions = append(solutions, solution)
+		return true
+	})
+	if err != nil || count != 2 {
+		t.Fatalf("Expected 2 models, but got %d, %v", count, err)
+	}
+	expected := []Solution{
+		sat([]int{
+			0, 0, 1, 0,
+			1, 0, 0, 0,
+			0, 0, 0, 1,
+			0, 1, 0, 0}),
+		sat([]int{
+			0, 1, 0, 0,
+			0, 0, 0, 1,
+			1, 0, 0, 0,
+			0, 0, 1, 0}),
+	}
+	if !(equalSolution(solutions[0], expected[0]) && equalSolution(solutions[1], expected[1]) ||
+		equalSolution(solutions[0], expected[1]) && equalSolution(solutions[1], expected[0])) {
+		t.Errorf("Expected the models %v, but got %v", expected, solutions)
+	}
+}
@@ -0,0 +1,249 @@
+// Package formula builds boolean formulas over the vars of a problem, and
+// encodes them to clauses for the solver.
+package formula
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/jvoung/s1t"
+)
+
+// Formula is a node made by a Builder, or the negation of one. Negation is
+// free: it only flips the lowest bit.
+type Formula int
+
+const (
+	// True is the constant formula which is always true.
+	True Formula = 0
+	// False is the constant formula which is always false.
+	False Formula = 1
+)
+
+func (f Formula) node() int {
+	return int(f >> 1)
+}
+
+func (f Formula) negated() bool {
+	return f&1 == 1
+}
+
+type op int
+
+const (
+	opTrue op = iota
+	opVar
+	opAnd
+	opXor
+	opIte
+)
+
+type node struct {
+	op       op
+	v        s1t.VarNum // var of an opVar node
+	children []Formula
+}
+
+// Builder makes formulas, and shares identical subformulas: building the same
+// formula twice returns the same Formula (hash-consing). It also simplifies
+// formulas with constants or repeated children.
+type Builder struct {
+	numVars int
+	nodes   []node
+	unique  map[string]Formula
+
+	// The encoding to clauses.
+	literals []int     // literal of each node, or none if not encoded yet
+	encoded  [][2]bool // whether each node's clauses for each polarity were added
+	nextVar  s1t.VarNum
+	clauses  []s1t.Clause
+}
+
+const none = -1
+
+// NewBuilder returns a builder of formulas over the vars of a problem with
+// numVars vars. The encoding adds vars after those.
+func NewBuilder(numVars int) *Builder {
+	b := &Builder{
+		numVars: numVars,
+		unique:  make(map[string]Formula),
+		nextVar: s1t.VarNum(numVars),
+	}
+	b.newNode(node{op: opTrue})
+	return b
+}
+
+func (b *Builder) newNode(n node) Formula {
+	b.nodes = append(b.nodes, n)
+	b.literals = append(b.literals, none)
+	b.encoded = append(b.encoded, [2]bool{})
+	return Formula(len(b.nodes)-1) << 1
+}
+
+// intern returns the formula for a node, which is new if no equal node exists.
+func (b *Builder) intern(n node) Formula {
+	key := []byte(strconv.Itoa(int(n.op)))
+	key = append(key, ':')
+	key = strconv.AppendInt(key, int64(n.v), 10)
+	for _, c := range n.children {
+		key = append(key, ' ')
+		key = strconv.AppendInt(key, int64(c), 10)
+	}
+	if f, ok := b.unique[string(key)]; ok {
+		return f
+	}
+	f := b.newNode(n)
+	b.unique[string(key)] = f
+	return f
+}
+
+// Var returns the formula for a var of the problem. It panics if the var is
+// beyond the builder's vars.
+func (b *Builder) Var(v s1t.VarNum) Formula {
+	if int(v) >= b.numVars {
+		panic(fmt.Sprintf("Variable %v goes beyond the %d vars", v, b.numVars))
+	}
+	return b.intern(node{op: opVar, v: v})
+}
+
+// Literal returns the formula for a literal of the problem.
+func (b *Builder) Literal(l s1t.Literal) Formula {
+	f := b.Var(l.Var())
+	if l.AsInt() == 0 {
+		return b.Not(f)
+	}
+	return f
+}
+
+// Not returns the negation of f.
+func (b *Builder) Not(f Formula) Formula {
+	return f ^ 1
+}
+
+// And returns the conjunction of the formulas, which is True if there are none.
+func (b *Builder) And(fs ...Formula) Formula {
+	var children []Formula
+	seen := make(map[Formula]bool)
+	for _, f := range fs {
+		if f == False || seen[b.Not(f)] {
+			return False
+		}
+		if f == True || seen[f] {
+			continue
+		}
+		seen[f] = true
+		children = append(children, f)
+	}
+	switch len(children) {
+	case 0:
+		return True
+	case 1:
+		return children[0]
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i] < children[j] })
+	return b.intern(node{op: opAnd, children: children})
+}
+
+// Or returns the disjunction of the formulas, which is False if there are none.
+func (b *Builder) Or(fs ...Formula) Formula {
+	negated := make([]Formula, len(fs))
+	for i, f := range fs {
+		negated[i] = b.Not(f)
+	}
+	return b.Not(b.And(negated...))
+}
+
+// Implies returns the formula for "x implies y".
+func (b *Builder) Implies(x, y Formula) Formula {
+	return b.Or(b.Not(x), y)
+}
+
+// Xor returns the formula which is true if exactly one of x and y is.
+func (b *Builder) Xor(x, y Formula) Formula {
+	// Negations are moved out, so the node has positive children.
+	negated := x.negated() != y.negated()
+	x &^= 1
+	y &^= 1
+	var f Formula
+	switch {
+	case x == y:
+		f = False
+	case x == True:
+		f = b.Not(y)
+	case y == True:
+		f = b.Not(x)
+	default:
+		if y < x {
+			x, y = y, x
+		}
+		f = b.intern(node{op: opXor, children: []Formula{x, y}})
+	}
+	if negated {
+		return b.Not(f)
+	}
+	return f
+}
+
+// Iff returns the formula which is true if x and y are equal.
+func (b *Builder) Iff(x, y Formula) Formula {
+	return b.Not(b.Xor(x, y))
+}
+
+// Ite returns the formula "if cond then x else y".
+func (b *Builder) Ite(cond, x, y Formula) Formula {
+	switch {
+	case cond == True || x == y:
+		return x
+	case cond == False:
+		return y
+	case cond.negated():
+		return b.Ite(b.Not(cond), y, x)
+	case x == True || x == cond:
+		return b.Or(cond, y)
+	case x == False || x == b.Not(cond):
+		return b.And(b.Not(cond), y)
+	case y == True || y == b.Not(cond):
+		return b.Or(b.Not(cond), x)
+	case y == False || y == cond:
+		return b.And(cond, x)
+	case x == b.Not(y):
+		return b.Iff(cond, x)
+	}
+	return b.intern(node{op: opIte, children: []Formula{cond, x, y}})
+}
+
+// Value returns the value of f for the assignment of the problem's vars in a
+// solution.
+func (b *Builder) Value(f Formula, solution s1t.Solution) bool {
+	return b.value(f, solution.Assignment, make(map[int]bool))
+}
+
+func (b *Builder) value(f Formula, assignment []int, memo map[int]bool) bool {
+	id := f.node()
+	v, ok := memo[id]
+	if !ok {
+		n := b.nodes[id]
+		switch n.op {
+		case opTrue:
+			v = true
+		case opVar:
+			v = assignment[n.v] == 1
+		case opAnd:
+			v = true
+			for _, c := range n.children {
+				v = v && b.value(c, assignment, memo)
+			}
+		case opXor:
+			v = b.value(n.children[0], assignment, memo) != b.value(n.children[1], assignment, memo)
+		case opIte:
+			if b.value(n.children[0], assignment, memo) {
+				v = b.value(n.children[1], assignment, memo)
+			} else {
+				v = b.value(n.children[2], assignment, memo)
+			}
+		}
+		memo[id] = v
+	}
+	return v != f.negated()
+}
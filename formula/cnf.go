@@ -0,0 +1,140 @@
+package formula
+
+import (
+	"github.com/jvoung/s1t"
+)
+
+// Assert adds the clauses which make f true. Conjunctions are split, and
+// disjunctions of subformulas become one clause. Other subformulas get a new
+// var, with clauses (Plaisted-Greenbaum) for only the polarity in which they
+// occur: the var implies the subformula if it occurs positively, and the
+// subformula implies the var if it occurs negatively.
+func (b *Builder) Assert(f Formula) {
+	n := b.nodes[f.node()]
+	switch {
+	case f == True:
+	case f == False:
+		b.addClause()
+	case n.op == opAnd && !f.negated():
+		for _, c := range n.children {
+			b.Assert(c)
+		}
+	case n.op == opAnd:
+		// The negation of an And is the Or of the negated children.
+		var clause []s1t.Literal
+		for _, c := range n.children {
+			clause = append(clause, b.encode(b.Not(c), true))
+		}
+		b.addClause(clause...)
+	default:
+		b.addClause(b.encode(f, true))
+	}
+}
+
+// Clauses returns the clauses of the asserted formulas.
+func (b *Builder) Clauses() []s1t.Clause {
+	return b.clauses
+}
+
+// NumVars returns the number of vars: the problem's vars, then those added for
+// the encoding.
+func (b *Builder) NumVars() int {
+	return int(b.nextVar)
+}
+
+// Problem returns a problem with the clauses of the asserted formulas.
+func (b *Builder) Problem() s1t.Problem {
+	return s1t.Problem{
+		Spec: s1t.ProblemSpec{
+			Format:          "cnf",
+			NumVariables:    b.NumVars(),
+			NumClauses:      len(b.clauses),
+			NumAuxVariables: b.NumVars() - b.numVars,
+		},
+		Clauses: b.clauses,
+	}
+}
+
+func (b *Builder) addClause(literals ...s1t.Literal) {
+	b.clauses = append(b.clauses, s1t.Clause{Literals: literals})
+}
+
+// encode returns a literal for f. If positive, the literal implies f, and
+// otherwise f implies the literal. Constants only occur at the top, since the
+// builder simplifies them away.
+func (b *Builder) encode(f Formula, positive bool) s1t.Literal {
+	id := f.node()
+	if f.negated() {
+		positive = !positive
+	}
+	n := b.nodes[id]
+	if b.literals[id] == none {
+		if n.op == opVar {
+			b.literals[id] = int(s1t.Positive(n.v))
+		} else {
+			b.literals[id] = int(b.newVar())
+		}
+	}
+	x := s1t.Literal(b.literals[id])
+	polarity := 0
+	if positive {
+		polarity = 1
+	}
+	if !b.encoded[id][polarity] {
+		b.encoded[id][polarity] = true
+		b.define(n, x, positive)
+	}
+	if f.negated() {
+		return x.Negate()
+	}
+	return x
+}
+
+// define adds the clauses for x implying node n if positive, or n implying x
+// otherwise.
+func (b *Builder) define(n node, x s1t.Literal, positive bool) {
+	switch n.op {
+	case opAnd:
+		if positive {
+			for _, c := range n.children {
+				b.addClause(x.Negate(), b.encode(c, true))
+			}
+		} else {
+			clause := []s1t.Literal{x}
+			for _, c := range n.children {
+				clause = append(clause, b.encode(c, false).Negate())
+			}
+			b.addClause(clause...)
+		}
+	case opXor:
+		// Both polarities of the children are needed.
+		y := b.encode(n.children[0], true)
+		b.encode(n.children[0], false)
+		z := b.encode(n.children[1], true)
+		b.encode(n.children[1], false)
+		if positive {
+			b.addClause(x.Negate(), y, z)
+			b.addClause(x.Negate(), y.Negate(), z.Negate())
+		} else {
+			b.addClause(x, y.Negate(), z)
+			b.addClause(x, y, z.Negate())
+		}
+	case opIte:
+		c := b.encode(n.children[0], true)
+		b.encode(n.children[0], false)
+		t := b.encode(n.children[1], positive)
+		e := b.encode(n.children[2], positive)
+		if positive {
+			b.addClause(x.Negate(), c.Negate(), t)
+			b.addClause(x.Negate(), c, e)
+		} else {
+			b.addClause(x, c.Negate(), t.Negate())
+			b.addClause(x, c, e.Negate())
+		}
+	}
+}
+
+func (b *Builder) newVar() s1t.Literal {
+	b.nextVar++
+	return s1t.Positive(b.nextVar - 1)
+}
@@ -0,0 +1,109 @@
+package formula
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/jvoung/s1t"
+)
+
+func TestHashConsing(t *testing.T) {
+	b := NewBuilder(3)
+	x, y, z := b.Var(0), b.Var(1), b.Var(2)
+	cases := []struct {
+		desc     string
+		got      Formula
+		expected Formula
+	}{
+		{"same var", b.Var(0), x},
+		{"commuted and", b.And(y, x), b.And(x, y)},
+		{"repeated and", b.And(x, y, x, True), b.And(x, y)},
+		{"contradiction", b.And(x, b.Not(x), y), False},
+		{"or by De Morgan", b.Or(x, y), b.Not(b.And(b.Not(x), b.Not(y)))},
+		{"empty or", b.Or(), False},
+		{"negated xor", b.Xor(b.Not(x), y), b.Not(b.Xor(y, x))},
+		{"xor with itself", b.Xor(x, b.Not(x)), True},
+		{"implies", b.Implies(x, y), b.Or(b.Not(x), y)},
+		{"negated condition", b.Ite(b.Not(x), y, z), b.Ite(x, z, y)},
+		{"constant branch", b.Ite(x, True, y), b.Or(x, y)},
+		{"equal branches", b.Ite(x, y, y), y},
+		{"literal", b.Literal(s1t.Negative(2)), b.Not(z)},
+	}
+	for _, c := range cases {
+		if c.got != c.expected {
+			t.Errorf("Case %q, expected formula %v, but got %v", c.desc, c.expected, c.got)
+		}
+	}
+	// True, 3 vars, and x&y, ~x&~y, x^y, x ? z : y, x&~y.
+	if len(b.nodes) != 9 {
+		t.Errorf("Expected 9 distinct nodes, but got %d", len(b.nodes))
+	}
+}
+
+func TestPolarity(t *testing.T) {
+	b := NewBuilder(4)
+	b.Assert(b.Or(b.And(b.Var(0), b.Var(1)), b.And(b.Var(2), b.Var(3))))
+	// Each And only needs that its var implies its children, in 2 clauses.
+	if len(b.Clauses()) != 5 || b.NumVars() != 6 {
+		t.Errorf("Expected 5 clauses over 6 vars, but got %v", b.Clauses())
+	}
+}
+
+// randomFormula returns a random formula over the builder's vars.
+func randomFormula(rng *rand.Rand, b *Builder, numVars, depth int) Formula {
+	if depth == 0 || rng.Intn(5) == 0 {
+		return b.Var(s1t.VarNum(rng.Intn(numVars)))
+	}
+	next := func() Formula { return randomFormula(rng, b, numVars, depth-1) }
+	switch rng.Intn(6) {
+	case 0:
+		return b.Not(next())
+	case 1:
+		return b.And(next(), next(), next())
+	case 2:
+		return b.Or(next(), next())
+	case 3:
+		return b.Xor(next(), next())
+	case 4:
+		return b.Implies(next(), next())
+	default:
+		return b.Ite(next(), next(), next())
+	}
+}
+
+func TestEncodingMatchesTruthTable(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const numVars = 6
+	for i := 0; i < 200; i++ {
+		b := NewBuilder(numVars)
+		f := randomFormula(rng, b, numVars, 5)
+		// Shared subformulas, and both polarities of some.
+		g := randomFormula(rng, b, numVars, 3)
+		f = b.And(f, b.Or(g, b.Var(0)))
+		b.Assert(f)
+		expected := 0
+		for bits := 0; bits < 1<<numVars; bits++ {
+			solution := s1t.Solution{Status: s1t.Sat, IsSat: true, Assignment: make([]int, numVars)}
+			for v := range solution.Assignment {
+				solution.Assignment[v] = (bits >> uint(v)) & 1
+			}
+			if b.Value(f, solution) {
+				expected++
+			}
+		}
+		problem := b.Problem()
+		for v := 0; v < numVars; v++ {
+			problem.Independent = append(problem.Independent, s1t.VarNum(v))
+		}
+		if n := s1t.Count(problem); n.Cmp(big.NewInt(int64(expected))) != 0 {
+			t.Errorf("Formula %d, expected %d models but counted %v", i, expected, n)
+		}
+		solution := s1t.Solve(problem)
+		if (solution.Status == s1t.Sat) != (expected > 0) {
+			t.Errorf("Formula %d, expected %d models but got %v", i, expected, solution)
+		} else if solution.Status == s1t.Sat && !b.Value(f, solution) {
+			t.Errorf("Formula %d, the solution %v makes it false", i, solution)
+		}
+	}
+}
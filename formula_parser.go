@@ -0,0 +1,166 @@
+// Parser for infix formulas with named vars, which converts them to clauses by
+// the Tseitin transformation.
+
+package s1t
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// ParseFormula parses an infix formula with named vars, such as
+// "(x1 | ~x5 | x2) & (~x1 | x5)", and transforms it to clauses. The operators
+// from the loosest to the tightest are <-> (iff), -> (implies, which groups
+// to the right), | (or), ^ (xor), & (and) and ~ (not). Vars are numbered in
+// the order they first appear, and named by problem.Symbols.
+func ParseFormula(in io.Reader) (Problem, error) {
+	text, err := ioutil.ReadAll(in)
+	if err != nil {
+		return Problem{}, err
+	}
+	tokens, err := tokenizeFormula(string(text))
+	if err != nil {
+		return Problem{}, err
+	}
+	p := formulaParser{tokens: tokens, symbols: NewSymbolTable()}
+	f, err := p.parseIff()
+	if err != nil {
+		return Problem{}, err
+	}
+	if p.pos != len(p.tokens) {
+		return Problem{}, fmt.Errorf("Unexpected %q after the formula", p.tokens[p.pos])
+	}
+	spec := ProblemSpec{Format: "formula", NumVariables: p.symbols.Len()}
+	t := tseitin{nextVar: VarNum(spec.NumVariables), trueLiteral: none}
+	t.assert(f)
+	spec.NumAuxVariables = int(t.nextVar) - spec.NumVariables
+	spec.NumVariables = int(t.nextVar)
+	spec.NumClauses = len(t.clauses)
+	return Problem{Spec: spec, Clauses: t.clauses, Symbols: p.symbols}, nil
+}
+
+// tokenizeFormula splits the text into names, operators and parentheses.
+func tokenizeFormula(text string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isNameChar(c) && !isDigit(c):
+			j := i + 1
+			for j < len(text) && isNameChar(text[j]) {
+				j++
+			}
+			tokens = append(tokens, text[i:j])
+			i = j
+		case strings.HasPrefix(text[i:], "->"):
+			tokens = append(tokens, "->")
+			i += len("->")
+		case strings.HasPrefix(text[i:], "<->"):
+			tokens = append(tokens, "<->")
+			i += len("<->")
+		case strings.IndexByte("~&|^()", c) >= 0:
+			tokens = append(tokens, text[i:i+1])
+			i++
+		default:
+			return nil, fmt.Errorf("Unexpected character %q in formula", c)
+		}
+	}
+	return tokens, nil
+}
+
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || isDigit(c)
+}
+
+// formulaParser parses infix formulas by recursive descent, with a method for
+// each level of precedence. It builds the same formulas as the "sat" format.
+type formulaParser struct {
+	tokens  []string
+	pos     int
+	symbols *SymbolTable
+}
+
+func (p *formulaParser) next(token string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos] == token {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *formulaParser) parseIff() (*formula, error) {
+	left, err := p.parseImplies()
+	for err == nil && p.next("<->") {
+		var right *formula
+		right, err = p.parseImplies()
+		left = &formula{op: "=", children: []*formula{left, right}}
+	}
+	return left, err
+}
+
+func (p *formulaParser) parseImplies() (*formula, error) {
+	left, err := p.parseOr()
+	if err != nil || !p.next("->") {
+		return left, err
+	}
+	right, err := p.parseImplies()
+	notLeft := &formula{op: "-", children: []*formula{left}}
+	return &formula{op: "+", children: []*formula{notLeft, right}}, err
+}
+
+func (p *formulaParser) parseOr() (*formula, error) {
+	return p.parseList("|", "+", p.parseXor)
+}
+
+func (p *formulaParser) parseXor() (*formula, error) {
+	return p.parseList("^", "xor", p.parseAnd)
+}
+
+func (p *formulaParser) parseAnd() (*formula, error) {
+	return p.parseList("&", "*", p.parseNot)
+}
+
+// parseList parses operands separated by token, as one formula with op if
+// there are several.
+func (p *formulaParser) parseList(token, op string,
+	parseOperand func() (*formula, error)) (*formula, error) {
+	f, err := parseOperand()
+	if err != nil || p.pos == len(p.tokens) || p.tokens[p.pos] != token {
+		return f, err
+	}
+	list := &formula{op: op, children: []*formula{f}}
+	for p.next(token) {
+		f, err = parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		list.children = append(list.children, f)
+	}
+	return list, nil
+}
+
+func (p *formulaParser) parseNot() (*formula, error) {
+	if p.pos == len(p.tokens) {
+		return nil, fmt.Errorf("Expected a formula, but the input ended")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch {
+	case token == "~":
+		f, err := p.parseNot()
+		return &formula{op: "-", children: []*formula{f}}, err
+	case token == "(":
+		f, err := p.parseIff()
+		if err == nil && !p.next(")") {
+			err = fmt.Errorf("Expected \")\" after the formula in parentheses")
+		}
+		return f, err
+	case isNameChar(token[0]):
+		return &formula{literal: Positive(p.symbols.Intern(token))}, nil
+	}
+	return nil, fmt.Errorf("Expected a formula, but got %q", token)
+}
@@ -0,0 +1,130 @@
+package s1t
+
+import (
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func parseFormulaOrDie(text string, t *testing.T) Problem {
+	problem, err := ParseFormula(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", text, err)
+	}
+	return problem
+}
+
+func TestParseFormula(t *testing.T) {
+	problem := parseFormulaOrDie("(x1 | ~x5 | x2) & (~x1 | x5 | x3 | x4)", t)
+	expected := []Clause{
+		{Literals: []Literal{Positive(0), Negative(1), Positive(2)}},
+		{Literals: []Literal{Negative(0), Positive(1), Positive(3), Positive(4)}},
+	}
+	if !equalClauses(problem.Clauses, expected) || problem.Spec.NumAuxVariables != 0 {
+		t.Errorf("Expected clauses %v without new vars, but got %v", expected, problem)
+	}
+	for i, name := range []string{"x1", "x5", "x2", "x3", "x4"} {
+		if v, ok := problem.Symbols.Var(name); !ok || v != VarNum(i) {
+			t.Errorf("Expected %s to be var %d, but got %v, %v", name, i, v, ok)
+		}
+	}
+
+	problem = parseFormulaOrDie("a & ~b & (c -> a ^ b)", t)
+	solution := Solve(problem)
+	if solution.Status != Sat {
+		t.Fatalf("Expected sat, but got %v", solution)
+	}
+	output := solution.Output(problem)
+	if !strings.Contains(output, "v a\nv ~b\n") || strings.Count(output, "v ") != 3 {
+		t.Errorf("Expected named vars in the output, but got %q", output)
+	}
+}
+
+func TestFormulaPrecedence(t *testing.T) {
+	cases := []struct{ formula, grouped string }{
+		{"a | b & c", "a | (b & c)"},
+		{"a ^ b & c", "a ^ (b & c)"},
+		{"a | b ^ c", "a | (b ^ c)"},
+		{"a ^ b ^ c", "(a ^ b) ^ c"},
+		{"a -> b -> c", "a -> (b -> c)"},
+		{"a -> b | c", "a -> (b | c)"},
+		{"a <-> b -> c", "a <-> (b -> c)"},
+		{"a <-> b <-> c", "(a <-> b) <-> c"},
+		{"~a & b", "(~a) & b"},
+		{"~~a | b", "a | b"},
+	}
+	for _, c := range cases {
+		// The formulas are equivalent if this is true for all values of the vars.
+		problem := parseFormulaOrDie("("+c.formula+") <-> ("+c.grouped+")", t)
+		all := int64(1) << uint(problem.Symbols.Len())
+		if n := Count(problem); n.Cmp(big.NewInt(all)) != 0 {
+			t.Errorf("Expected %q to mean %q, but they agree on %v of %d values",
+				c.formula, c.grouped, n, all)
+		}
+	}
+}
+
+// randomInfix returns a random infix formula over numVars vars named x0 and
+// so on, and the same formula as in the "sat" format.
+func randomInfix(rng *rand.Rand, numVars, depth int) (string, *formula) {
+	if depth == 0 || rng.Intn(4) == 0 {
+		v := rng.Intn(numVars)
+		return "x" + strconv.Itoa(v), &formula{literal: Positive(VarNum(v))}
+	}
+	a, fa := randomInfix(rng, numVars, depth-1)
+	b, fb := randomInfix(rng, numVars, depth-1)
+	not := func(f *formula) *formula { return &formula{op: "-", children: []*formula{f}} }
+	switch rng.Intn(6) {
+	case 0:
+		return "~" + a, not(fa)
+	case 1:
+		return "(" + a + " & " + b + ")", &formula{op: "*", children: []*formula{fa, fb}}
+	case 2:
+		return "(" + a + " | " + b + ")", &formula{op: "+", children: []*formula{fa, fb}}
+	case 3:
+		return "(" + a + " ^ " + b + ")", &formula{op: "xor", children: []*formula{fa, fb}}
+	case 4:
+		return "(" + a + " -> " + b + ")", &formula{op: "+", children: []*formula{not(fa), fb}}
+	default:
+		return "(" + a + " <-> " + b + ")", &formula{op: "=", children: []*formula{fa, fb}}
+	}
+}
+
+func TestParseFormulaMatchesTruthTable(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const numVars = 5
+	for i := 0; i < 200; i++ {
+		text, f := randomInfix(rng, numVars, 5)
+		problem := parseFormulaOrDie(text, t)
+		expected := 0
+		for bits := 0; bits < 1<<numVars; bits++ {
+			if evalFormula(f, bits) {
+				expected++
+			}
+		}
+		// Vars that don't appear double the models of the truth table.
+		expected >>= uint(numVars - problem.Symbols.Len())
+		if n := Count(problem); n.Cmp(big.NewInt(int64(expected))) != 0 {
+			t.Errorf("Formula %s, expected %d models but counted %v", text, expected, n)
+		}
+	}
+}
+
+func TestParseFormulaErrorCases(t *testing.T) {
+	cases := []struct{ formula, expectedErrSubstring string }{
+		{"a &", "Expected a formula, but the input ended"},
+		{"(a | b", "Expected \")\""},
+		{"a b", "Unexpected \"b\" after the formula"},
+		{"a $ b", "Unexpected character '$'"},
+		{"a | & b", "Expected a formula, but got \"&\""},
+	}
+	for _, c := range cases {
+		_, err := ParseFormula(strings.NewReader(c.formula))
+		if err == nil || !strings.Contains(err.Error(), c.expectedErrSubstring) {
+			t.Errorf("Formula %q, expected err string %q but got %v",
+				c.formula, c.expectedErrSubstring, err)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Decision heuristics: which variable to branch on next, and with which phase.
+
+package s1t
+
+import (
+	"math/rand"
+)
+
+// Heuristic selects how the solver picks the next decision variable.
+type Heuristic int
+
+const (
+	// EVSIDS picks the unassigned variable with the highest activity. Variables
+	// involved in a conflict are bumped by an increment which grows after each
+	// conflict, so older bumps decay exponentially (as in MiniSat).
+	EVSIDS Heuristic = iota
+	// VSIDS bumps the variables of each learned clause by one and periodically
+	// halves all activities (as in Chaff).
+	VSIDS
+	// Ordered picks the lowest numbered unassigned variable.
+	Ordered
+)
+
+// Phase selects which value a decision variable is tried with.
+type Phase int
+
+const (
+	// PhaseSaving reuses the value a variable had when it was last unassigned
+	// (positive if never assigned).
+	PhaseSaving Phase = iota
+	// PhasePositive always tries the positive literal first.
+	PhasePositive
+	// PhaseNegative always tries the negative literal first.
+	PhaseNegative
+)
+
+const (
+	evsidsDecay       = 0.95
+	activityLimit     = 1e100
+	vsidsHalvingEvery = 256
+	// Random initial activities are below the smallest bump.
+	seededActivity = 1e-5
+)
+
+// decider tracks variable activities and saved phases to pick decisions.
+type decider struct {
+	heuristic Heuristic
+	phase     Phase
+	activity  []float64
+	increment float64
+	order     varHeap
+	saved     []int // last assigned value of each var, as in Literal.AsInt
+	conflicts int
+}
+
+// A non-zero seed gives each variable a small random initial activity, to
+// shuffle the initial order of the activity heuristics.
+func newDecider(numVars int, heuristic Heuristic, phase Phase, seed int64) decider {
+	d := decider{
+		heuristic: heuristic,
+		phase:     phase,
+		activity:  make([]float64, numVars),
+		increment: 1,
+		saved:     make([]int, numVars),
+	}
+	if seed != 0 {
+		rng := rand.New(rand.NewSource(seed))
+		for v := range d.activity {
+			d.activity[v] = rng.Float64() * seededActivity
+		}
+	}
+	d.order = newVarHeap(d.activity)
+	for v := 0; v < numVars; v++ {
+		d.saved[v] = 1
+		d.order.insert(VarNum(v))
+	}
+	return d
+}
+
+// next returns the literal to decide on, or false if all vars are assigned.
+func (d *decider) next(assignments []int) (Literal, bool) {
+	var v VarNum
+	if d.heuristic == Ordered {
+		var hasUnassigned bool
+		v, hasUnassigned = nextUnassignedVariable(assignments, 0)
+		if !hasUnassigned {
+			return 0, false
+		}
+	} else {
+		for {
+			if d.order.empty() {
+				return 0, false
+			}
+			v = d.order.removeMax()
+			if assignments[v] == none {
+				break
+			}
+		}
+	}
+	switch {
+	case d.phase == PhaseNegative:
+		return Negative(v), true
+	case d.phase == PhaseSaving && d.saved[v] == 0:
+		return Negative(v), true
+	default:
+		return Positive(v), true
+	}
+}
+
+// unassigned makes v available for decisions again, remembering its value.
+func (d *decider) unassigned(l Literal) {
+	v := l.Var()
+	d.saved[v] = l.AsInt()
+	if !d.order.contains(v) {
+		d.order.insert(v)
+	}
+}
+
+// newVar makes room for a variable added after the decider was created.
+func (d *decider) newVar() {
+	v := VarNum(len(d.activity))
+	d.activity = append(d.activity, 0)
+	d.saved = append(d.saved, 1)
+	d.order.activity = d.activity
+	d.order.indices = append(d.order.indices, none)
+	d.order.insert(v)
+}
+
+// bumpConflict is called for each variable seen during conflict analysis.
+func (d *decider) bumpConflict(v VarNum) {
+	if d.heuristic == EVSIDS {
+		d.bump(v, d.increment)
+	}
+}
+
+// bumpLearned is called for each literal of a learned clause.
+func (d *decider) bumpLearned(l Literal) {
+	if d.heuristic == VSIDS {
+		d.bump(l.Var(), 1)
+	}
+}
+
+// decay is called once per conflict, after bumping.
+func (d *decider) decay() {
+	d.conflicts++
+	switch d.heuristic {
+	case EVSIDS:
+		d.increment /= evsidsDecay
+		if d.increment > activityLimit {
+			d.rescale(1 / activityLimit)
+		}
+	case VSIDS:
+		if d.conflicts%vsidsHalvingEvery == 0 {
+			d.rescale(0.5)
+		}
+	}
+}
+
+func (d *decider) bump(v VarNum, amount float64) {
+	d.activity[v] += amount
+	if d.activity[v] > activityLimit {
+		d.rescale(1 / activityLimit)
+	}
+	if d.order.contains(v) {
+		d.order.increased(v)
+	}
+}
+
+// rescale multiplies all activities by factor, which keeps the heap order.
+func (d *decider) rescale(factor float64) {
+	for i := range d.activity {
+		d.activity[i] *= factor
+	}
+	if d.heuristic == EVSIDS {
+		d.increment *= factor
+	}
+}
+
+// varHeap is a binary max-heap of variables ordered by activity.
+type varHeap struct {
+	activity []float64
+	heap     []VarNum
+	indices  []int // position of each var in heap, or none
+}
+
+func newVarHeap(activity []float64) varHeap {
+	indices := make([]int, len(activity))
+	for i := range indices {
+		indices[i] = none
+	}
+	return varHeap{
+		activity: activity,
+		heap:     make([]VarNum, 0, len(activity)),
+		indices:  indices,
+	}
+}
+
+func (h *varHeap) empty() bool {
+	return len(h.heap) == 0
+}
+
+func (h *varHeap) contains(v VarNum) bool {
+	return h.indices[v] != none
+}
+
+func (h *varHeap) insert(v VarNum) {
+	h.indices[v] = len(h.heap)
+	h.heap = append(h.heap, v)
+	h.up(h.indices[v])
+}
+
+// increased restores the heap order after v's activity went up.
+func (h *varHeap) increased(v VarNum) {
+	h.up(h.indices[v])
+}
+
+func (h *varHeap) removeMax() VarNum {
+	top := h.heap[0]
+	last := h.heap[len(h.heap)-1]
+	h.heap = h.heap[:len(h.heap)-1]
+	h.indices[top] = none
+	if len(h.heap) > 0 {
+		h.heap[0] = last
+		h.indices[last] = 0
+		h.down(0)
+	}
+	return top
+}
+
+func (h *varHeap) less(i, j int) bool {
+	return h.activity[h.heap[i]] < h.activity[h.heap[j]]
+}
+
+func (h *varHeap) swap(i, j int) {
+	h.heap[i], h.heap[j] = h.heap[j], h.heap[i]
+	h.indices[h.heap[i]] = i
+	h.indices[h.heap[j]] = j
+}
+
+func (h *varHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !h.less(parent, i) {
+			return
+		}
+		h.swap(parent, i)
+		i = parent
+	}
+}
+
+func (h *varHeap) down(i int) {
+	for {
+		child := 2*i + 1
+		if child >= len(h.heap) {
+			return
+		}
+		if right := child + 1; right < len(h.heap) && h.less(child, right) {
+			child = right
+		}
+		if !h.less(i, child) {
+			return
+		}
+		h.swap(i, child)
+		i = child
+	}
+}
@@ -0,0 +1,64 @@
+package s1t
+
+import (
+	"testing"
+)
+
+func TestVarHeapOrder(t *testing.T) {
+	activity := []float64{3, 1, 4, 1, 5, 9, 2, 6}
+	h := newVarHeap(activity)
+	for v := range activity {
+		h.insert(VarNum(v))
+	}
+	activity[1] = 7
+	h.increased(1)
+	expectedOrder := []VarNum{5, 1, 7, 4, 2, 0, 6, 3}
+	for i, expected := range expectedOrder {
+		if h.empty() {
+			t.Fatalf("Heap empty after %d removals", i)
+		}
+		if v := h.removeMax(); v != expected {
+			t.Errorf("Removal %d: expected v%d but got v%d", i, expected, v)
+		}
+	}
+	if !h.empty() {
+		t.Error("Expected empty heap")
+	}
+}
+
+func TestPhaseSaving(t *testing.T) {
+	d := newDecider(2, EVSIDS, PhaseSaving, 0)
+	assignments := initialAssignments(2)
+	d.bump(1, 1)
+	l, ok := d.next(assignments)
+	if !ok || l != Positive(1) {
+		t.Fatalf("Expected most active v1 with positive default phase, but got %v", l)
+	}
+	d.unassigned(Negative(1))
+	l, ok = d.next(assignments)
+	if !ok || l != Negative(1) {
+		t.Errorf("Expected saved phase ¬v1, but got %v", l)
+	}
+	assignments[1] = 0
+	l, ok = d.next(assignments)
+	if !ok || l != Positive(0) {
+		t.Errorf("Expected v0 next, but got %v", l)
+	}
+	assignments[0] = 1
+	if l, ok = d.next(assignments); ok {
+		t.Errorf("Expected no decision with all vars assigned, but got %v", l)
+	}
+}
+
+func TestHeuristicsAndPhases(t *testing.T) {
+	for _, heuristic := range []Heuristic{EVSIDS, VSIDS, Ordered} {
+		for _, phase := range []Phase{PhaseSaving, PhasePositive, PhaseNegative} {
+			opts := Options{Heuristic: heuristic, Phase: phase}
+			testFromFileWithOptions(t, "test_cnf/hole6.cnf", opts, unsat())
+			testFromFileWithOptions(t, "test_cnf/queen3.cnf", opts, unsat())
+			testFromFileSelfCheckWithOptions(t, "test_cnf/blocksworld_medium.cnf", opts)
+			testFromFileSelfCheckWithOptions(t, "test_cnf/RTI_k3_n100_m429_0.cnf", opts)
+			testFromFileSelfCheckWithOptions(t, "test_cnf/subsetsum2.cnf", opts)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Incremental interface: add variables and clauses between calls to Solve.
+
+package s1t
+
+import (
+	"fmt"
+)
+
+// NumVars returns the number of variables, including those from NewVar.
+func (s *Solver) NumVars() int {
+	return len(s.trail.assignments)
+}
+
+// NewVar adds a fresh variable and returns it.
+func (s *Solver) NewVar() VarNum {
+	s.backjump(0)
+	v := VarNum(s.NumVars())
+	t := &s.trail
+	t.assignments = append(t.assignments, none)
+	t.level = append(t.level, 0)
+	t.reason = append(t.reason, noReason)
+	t.index = append(t.index, 0)
+	s.seen = append(s.seen, false)
+	s.levelStamps = append(s.levelStamps, 0)
+	s.wls.literalToClause = append(s.wls.literalToClause, nil, nil)
+	s.pb.occurs = append(s.pb.occurs, nil, nil)
+	s.pb.reason = append(s.pb.reason, 0)
+	s.xors.basicRow = append(s.xors.basicRow, none)
+	s.xors.watches = append(s.xors.watches, nil)
+	s.elim.stacked = append(s.elim.stacked, false)
+	s.decider.newVar()
+	if s.core != nil {
+		s.core.newVar()
+	}
+	return v
+}
+
+// AddClause adds a clause (a disjunction of the literals) to the problem.
+// Learned clauses and heuristic state are kept for the next call to Solve.
+// Returns an error if a literal's variable does not exist.
+func (s *Solver) AddClause(literals ...Literal) error {
+	for _, l := range literals {
+		if int(l.Var()) >= s.NumVars() {
+			return fmt.Errorf("Variable %v goes beyond the %d vars", l.Var(), s.NumVars())
+		}
+	}
+	s.restore(literals)
+	c := Clause{Literals: withoutDuplicates(literals)}
+	ok := s.toLevelZero()
+	cnum := s.appendClause(c)
+	if s.core != nil {
+		s.core.addInput(cnum)
+	}
+	if !ok {
+		return nil
+	}
+	switch len(c.Literals) {
+	case 0:
+		s.setUnsat(cnum)
+		return nil
+	case 1:
+		if !s.enqueue(c.Literals[0], cnum) {
+			s.setUnsat(cnum)
+		} else if conflict := s.propagate(); conflict != noReason {
+			s.setUnsat(conflict)
+		}
+		return nil
+	}
+	// Watch the two best literals by their value at level 0: true, then
+	// unassigned, then false.
+	w1, w2 := s.bestWatches(c.Literals)
+	s.wls.watch(cnum, w1, w2)
+	switch {
+	case s.isFalse(w1):
+		s.setUnsat(cnum)
+	case s.isFalse(w2) && !s.isTrue(w1):
+		s.enqueue(w1, cnum)
+		if conflict := s.propagate(); conflict != noReason {
+			s.setUnsat(conflict)
+		}
+	}
+	return nil
+}
+
+// toLevelZero backjumps to decision level 0 and the first time, propagates
+// the problem's unit clauses. Returns false if the problem is known unsat.
+func (s *Solver) toLevelZero() bool {
+	s.backjump(0)
+	if !s.ok {
+		return false
+	}
+	if !s.propagatedUnits {
+		s.propagatedUnits = true
+		if conflict := s.initialUnitPropagate(); conflict != noReason {
+			s.setUnsat(conflict)
+		}
+	}
+	return s.ok
+}
+
+// appendClause stores a new clause in a new slot, without watching it.
+func (s *Solver) appendClause(c Clause) ClauseNum {
+	cnum := ClauseNum(len(s.clauses))
+	s.clauses = append(s.clauses, c)
+	s.wls.clauseToLiteral = append(s.wls.clauseToLiteral, nil)
+	s.learned.grow()
+	if s.core != nil {
+		s.core.newClause()
+	}
+	return cnum
+}
+
+func (s *Solver) bestWatches(literals []Literal) (Literal, Literal) {
+	rank := func(l Literal) int {
+		switch {
+		case s.isTrue(l):
+			return 0
+		case s.isFalse(l):
+			return 2
+		default:
+			return 1
+		}
+	}
+	w1, w2 := literals[0], literals[1]
+	if rank(w2) < rank(w1) {
+		w1, w2 = w2, w1
+	}
+	for _, l := range literals[2:] {
+		if rank(l) < rank(w1) {
+			w1, w2 = l, w1
+		} else if rank(l) < rank(w2) {
+			w2 = l
+		}
+	}
+	return w1, w2
+}
+
+func (s *Solver) isTrue(l Literal) bool {
+	return s.trail.assignments[l.Var()] == l.AsInt()
+}
+
+func (s *Solver) isFalse(l Literal) bool {
+	return s.trail.assignments[l.Var()] == l.Negate().AsInt()
+}
+
+func withoutDuplicates(literals []Literal) []Literal {
+	seen := make(map[Literal]bool, len(literals))
+	result := make([]Literal, 0, len(literals))
+	for _, l := range literals {
+		if !seen[l] {
+			seen[l] = true
+			result = append(result, l)
+		}
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+package s1t
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// Blocks the model in solution, so the next Solve finds a different one.
+func blockModel(t *testing.T, s *Solver, solution Solution) {
+	var block []Literal
+	for v, value := range solution.Assignment {
+		if value == 1 {
+			block = append(block, Negative(VarNum(v)))
+		} else {
+			block = append(block, Positive(VarNum(v)))
+		}
+	}
+	if err := s.AddClause(block...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnumerateByBlockingModels(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 3 1",
+		"1 2 3 0",
+	}, t)
+	s := NewSolver(problem, Options{})
+	seen := make(map[string]bool)
+	for {
+		solution, err := s.Solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if solution.Status == Unsat {
+			break
+		}
+		if ok, _ := solution.Satisfies(problem); !ok {
+			t.Fatalf("Solution %v doesn't satisfy the problem", solution)
+		}
+		key := fmt.Sprint(solution.Assignment)
+		if seen[key] {
+			t.Fatalf("Model found twice: %v", solution)
+		}
+		seen[key] = true
+		blockModel(t, s, solution)
+	}
+	if len(seen) != 7 {
+		t.Errorf("Expected 7 models, but got %d", len(seen))
+	}
+	// Stays unsat.
+	if solution, _ := s.Solve(); solution.Status != Unsat {
+		t.Errorf("Expected unsat but got %v", solution)
+	}
+}
+
+func TestAddClauseKeepsLearnedClauses(t *testing.T) {
+	input, err := os.Open("test_cnf/RTI_k3_n100_m429_499.cnf")
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	defer input.Close()
+	problem := parseOrDie(input, t)
+	s := NewSolver(problem, Options{})
+	solution, _ := s.Solve()
+	if solution.Status != Sat {
+		t.Fatalf("Expected sat but got %v", solution)
+	}
+	stats := s.Stats()
+	if stats.Learned == 0 {
+		t.Fatalf("Expected some learned clauses, but got %+v", stats)
+	}
+	blockModel(t, s, solution)
+	solution, _ = s.Solve()
+	if solution.Status == Unknown {
+		t.Fatalf("Expected an answer but got %v", solution)
+	}
+	if solution.Status == Sat {
+		if ok, _ := solution.Satisfies(problem); !ok {
+			t.Errorf("Solution %v doesn't satisfy the problem", solution)
+		}
+	}
+	if s.learned.count == 0 || s.Stats().Learned < stats.Learned {
+		t.Errorf("Expected statistics and learned clauses to carry over, but got %+v", s.Stats())
+	}
+}
+
+func TestNewVar(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 1 1",
+		"1 0",
+	}, t)
+	s := NewSolver(problem, Options{})
+	if err := s.AddClause(Positive(1)); err == nil {
+		t.Error("Expected an error for an undeclared variable")
+	}
+	a := s.NewVar()
+	b := s.NewVar()
+	if a != 1 || b != 2 || s.NumVars() != 3 {
+		t.Fatalf("Unexpected new vars %v %v with %d vars", a, b, s.NumVars())
+	}
+	// 0 -> a, a -> b.
+	if err := s.AddClause(Negative(0), Positive(a)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddClause(Negative(a), Positive(b)); err != nil {
+		t.Fatal(err)
+	}
+	solution, _ := s.Solve()
+	if !equalSolution(solution, sat([]int{1, 1, 1})) {
+		t.Errorf("Expected all true, but got %v", solution)
+	}
+	if err := s.AddClause(Negative(b)); err != nil {
+		t.Fatal(err)
+	}
+	if solution, _ := s.Solve(); solution.Status != Unsat {
+		t.Errorf("Expected unsat but got %v", solution)
+	}
+}
+
+func TestAddClauseAfterPartialAssignment(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 4 2",
+		"-1 -2 0",
+		"-3 4 0",
+	}, t)
+	s := NewSolver(problem, Options{})
+	// A tautology, and a clause with a repeated literal which becomes unit
+	// once 1 is propagated: 2 2 3 implies 3, which implies 4.
+	for _, c := range [][]Literal{
+		{Positive(0)},
+		{Negative(0), Positive(0)},
+		{Positive(1), Positive(1), Positive(2)},
+	} {
+		if err := s.AddClause(c...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	solution, _ := s.Solve()
+	if !equalSolution(solution, sat([]int{1, 0, 1, 1})) {
+		t.Errorf("Expected 1 -2 3 4, but got %v", solution)
+	}
+	if err := s.AddClause(); err != nil {
+		t.Fatal(err)
+	}
+	if solution, _ := s.Solve(); solution.Status != Unsat {
+		t.Errorf("Expected unsat after an empty clause, but got %v", solution)
+	}
+}
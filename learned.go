@@ -0,0 +1,190 @@
+// Learned clause database, with periodic reduction of the least useful clauses.
+
+package s1t
+
+import (
+	"sort"
+)
+
+const (
+	// Learned clauses with at most this LBD ("glue" clauses) are never deleted.
+	glueLBD = 2
+	// First reduction happens after this many conflicts (by default), and the
+	// interval grows by reduceIncrement after each reduction, as in Glucose.
+	defaultReduceInterval = 2000
+	reduceIncrement       = 300
+	clauseDecay           = 0.999
+	clauseActivityLimit   = 1e20
+)
+
+// learnedDB tracks the learned clauses, which are stored along with the
+// problem clauses in Solver.clauses. Slots of deleted clauses are reused.
+type learnedDB struct {
+	// Indexed by ClauseNum. Only live learned clauses are marked learned.
+	learned  []bool
+	lbd      []int
+	activity []float64
+	// Explanation clauses of constraints which are not clauses, which are
+	// neither learned nor problem clauses, and are freed once they are not
+	// reasons.
+	explanation []bool
+
+	free       []ClauseNum // deleted slots, to reuse
+	count      int         // number of live learned clauses
+	increment  float64
+	interval   int
+	nextReduce int // number of conflicts at which to reduce next
+}
+
+func newLearnedDB(numProblemClauses int, reduceInterval int) learnedDB {
+	if reduceInterval <= 0 {
+		reduceInterval = defaultReduceInterval
+	}
+	return learnedDB{
+		learned:     make([]bool, numProblemClauses),
+		lbd:         make([]int, numProblemClauses),
+		activity:    make([]float64, numProblemClauses),
+		explanation: make([]bool, numProblemClauses),
+		increment:   1,
+		interval:    reduceInterval,
+		nextReduce:  reduceInterval,
+	}
+}
+
+func (db *learnedDB) isLearned(cnum ClauseNum) bool {
+	return db.learned[cnum]
+}
+
+// grow makes room for metadata of a new clause slot.
+func (db *learnedDB) grow() {
+	db.learned = append(db.learned, false)
+	db.lbd = append(db.lbd, 0)
+	db.activity = append(db.activity, 0)
+	db.explanation = append(db.explanation, false)
+}
+
+// bump increases the activity of a learned clause used in conflict analysis.
+func (db *learnedDB) bump(cnum ClauseNum) {
+	db.activity[cnum] += db.increment
+	if db.activity[cnum] > clauseActivityLimit {
+		for j := range db.activity {
+			db.activity[j] /= clauseActivityLimit
+		}
+		db.increment /= clauseActivityLimit
+	}
+}
+
+// decay is called once per conflict.
+func (db *learnedDB) decay() {
+	db.increment /= clauseDecay
+}
+
+// updateLBD lowers the recorded LBD of a learned clause, if it improved.
+func (db *learnedDB) updateLBD(cnum ClauseNum, lbd int) {
+	if lbd < db.lbd[cnum] {
+		db.lbd[cnum] = lbd
+	}
+}
+
+// addLearnedClause stores a learned clause and watches its first two literals.
+// Unit learned clauses are not watched, like unit problem clauses.
+func (s *Solver) addLearnedClause(literals []Literal, lbd int) ClauseNum {
+	db := &s.learned
+	cnum := s.allocateClause(literals)
+	db.learned[cnum] = true
+	db.lbd[cnum] = lbd
+	db.activity[cnum] = 0
+	db.count++
+	db.bump(cnum)
+	if len(literals) >= 2 {
+		s.wls.watch(cnum, literals[0], literals[1])
+	}
+	return cnum
+}
+
+// allocateClause stores a clause in a free slot, or else a new one.
+func (s *Solver) allocateClause(literals []Literal) ClauseNum {
+	db := &s.learned
+	if len(db.free) > 0 {
+		cnum := db.free[len(db.free)-1]
+		db.free = db.free[:len(db.free)-1]
+		s.clauses[cnum] = Clause{Literals: literals}
+		return cnum
+	}
+	return s.appendClause(Clause{Literals: literals})
+}
+
+// addExplanation stores a clause implied by a constraint which is not a
+// clause, as the reason for propagating literals[0], or else to explain a
+// conflict. The other literals must be false. The clause is not watched, and
+// is freed by freeExplanation once it is no longer needed, except at level 0.
+func (s *Solver) addExplanation(literals []Literal) ClauseNum {
+	cnum := s.allocateClause(literals)
+	s.learned.explanation[cnum] = true
+	if s.core != nil {
+		// Cores only list input clauses, so the constraint is left out.
+		s.core.clauseNode[cnum] = s.core.newNode(none, nil)
+	}
+	return cnum
+}
+
+// freeExplanation frees the slot of an explanation clause, which is not in
+// the proof, since the proof does not cover constraints.
+func (s *Solver) freeExplanation(cnum ClauseNum) {
+	db := &s.learned
+	s.clauses[cnum] = Clause{}
+	db.explanation[cnum] = false
+	db.free = append(db.free, cnum)
+}
+
+// reduceLearned deletes about half of the learned clauses, keeping glue
+// clauses and clauses that are the reason for a current assignment.
+// Must not be called during propagation.
+func (s *Solver) reduceLearned() {
+	db := &s.learned
+	var candidates []ClauseNum
+	for i, learned := range db.learned {
+		cnum := ClauseNum(i)
+		if !learned || db.lbd[cnum] <= glueLBD || s.locked(cnum) {
+			continue
+		}
+		candidates = append(candidates, cnum)
+	}
+	// Worst first: highest LBD, then lowest activity.
+	sort.Slice(candidates, func(a, b int) bool {
+		ca, cb := candidates[a], candidates[b]
+		if db.lbd[ca] != db.lbd[cb] {
+			return db.lbd[ca] > db.lbd[cb]
+		}
+		return db.activity[ca] < db.activity[cb]
+	})
+	for _, cnum := range candidates[:len(candidates)/2] {
+		s.deleteLearned(cnum)
+	}
+	db.interval += reduceIncrement
+	db.nextReduce = s.stats.Conflicts + db.interval
+}
+
+// locked returns true if the clause is the reason for an assigned literal.
+func (s *Solver) locked(cnum ClauseNum) bool {
+	for _, l := range s.clauses[cnum].Literals {
+		v := l.Var()
+		if s.trail.reason[v] == cnum && s.trail.assignments[v] == l.AsInt() {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Solver) deleteLearned(cnum ClauseNum) {
+	db := &s.learned
+	if s.proof != nil {
+		s.proof.delete(s.clauses[cnum].Literals)
+	}
+	s.wls.detach(cnum)
+	s.clauses[cnum] = Clause{}
+	db.learned[cnum] = false
+	db.free = append(db.free, cnum)
+	db.count--
+	s.stats.Deleted++
+}
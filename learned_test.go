@@ -0,0 +1,85 @@
+package s1t
+
+import (
+	"os"
+	"testing"
+)
+
+// checkWatches verifies that the watch lists and clauseToLiteral agree, and
+// that deleted clauses are not watched.
+func checkWatches(t *testing.T, s *Solver) {
+	counts := make(map[ClauseNum]int)
+	for l, clauses := range s.wls.literalToClause {
+		for _, cnum := range clauses {
+			counts[cnum]++
+			watched := s.wls.clauseToLiteral[cnum]
+			if watched == nil {
+				t.Fatalf("c%d is on the watch list of %v but has no watches", cnum, Literal(l))
+			}
+			if watched.first != Literal(l) && watched.second != Literal(l) {
+				t.Fatalf("c%d is on the watch list of %v but watches %v", cnum, Literal(l), *watched)
+			}
+		}
+	}
+	for _, cnum := range s.learned.free {
+		if s.wls.clauseToLiteral[cnum] != nil || counts[cnum] != 0 {
+			t.Fatalf("Deleted c%d is still watched", cnum)
+		}
+	}
+	for i, watched := range s.wls.clauseToLiteral {
+		cnum := ClauseNum(i)
+		if watched != nil && counts[cnum] != 2 {
+			t.Fatalf("c%d is on %d watch lists instead of 2", cnum, counts[cnum])
+		}
+	}
+}
+
+func TestReduceLearned(t *testing.T) {
+	input, err := os.Open("test_cnf/hole6.cnf")
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	defer input.Close()
+	problem := parseOrDie(input, t)
+	s := NewSolver(problem, Options{ReduceInterval: 20})
+	if solution, err := s.Solve(); err != nil || solution.Status != Unsat {
+		t.Fatalf("Expected unsat but got %v, %v", solution, err)
+	}
+	if s.stats.Deleted == 0 {
+		t.Errorf("Expected some learned clauses to be deleted: %+v", s.stats)
+	}
+	if s.learned.count != s.stats.Learned-s.stats.Deleted {
+		t.Errorf("Expected %d live learned clauses, but got %d",
+			s.stats.Learned-s.stats.Deleted, s.learned.count)
+	}
+	checkWatches(t, s)
+
+	// Glue clauses and clauses that are reasons survive a reduction.
+	var locked []ClauseNum
+	for i, learned := range s.learned.learned {
+		if cnum := ClauseNum(i); learned && s.locked(cnum) {
+			locked = append(locked, cnum)
+		}
+	}
+	s.reduceLearned()
+	checkWatches(t, s)
+	for _, cnum := range locked {
+		if !s.learned.isLearned(cnum) {
+			t.Errorf("Deleted c%d which is a reason", cnum)
+		}
+	}
+	for _, cnum := range s.learned.free {
+		if s.learned.lbd[cnum] <= glueLBD {
+			t.Errorf("Deleted glue clause c%d", cnum)
+		}
+	}
+}
+
+func TestSolveWithFrequentReductions(t *testing.T) {
+	opts := Options{ReduceInterval: 10}
+	testFromFileWithOptions(t, "test_cnf/hole6.cnf", opts, unsat())
+	testFromFileWithOptions(t, "test_cnf/queen3.cnf", opts, unsat())
+	testFromFileSelfCheckWithOptions(t, "test_cnf/blocksworld_medium.cnf", opts)
+	testFromFileSelfCheckWithOptions(t, "test_cnf/RTI_k3_n100_m429_0.cnf", opts)
+	testFromFileSelfCheckWithOptions(t, "test_cnf/subsetsum3.cnf", opts)
+}
@@ -0,0 +1,205 @@
+// MaxSAT: minimizing the weight of the falsified soft clauses, with the
+// core-guided OLL algorithm and stratification by weight.
+
+package s1t
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// SolveMaxSat returns a solution of problem's clauses which minimizes the cost
+// of problem.Soft (see Solution.Cost), or an Unsat solution if the clauses are
+// unsat by themselves. It calls improved with each better solution found.
+func SolveMaxSat(problem Problem, opts Options, improved func(Solution)) (Solution, error) {
+	return NewSolver(problem, opts).SolveMaxSat(problem.Soft, improved)
+}
+
+// SolveMaxSat is like the SolveMaxSat function, but for the solver's clauses.
+// It adds vars and clauses to relax the soft clauses, so the solver is only
+// good for more calls to SolveMaxSat with the same soft clauses afterwards.
+func (s *Solver) SolveMaxSat(soft []SoftClause, improved func(Solution)) (Solution, error) {
+	return s.SolveMaxSatContext(context.Background(), soft, improved)
+}
+
+// SolveMaxSatContext is like SolveMaxSat, but if ctx is done (or a budget is
+// exhausted) first, it gives up with the error and the best solution found so
+// far: Sat but maybe not optimal, or Unknown if there is none.
+func (s *Solver) SolveMaxSatContext(ctx context.Context, soft []SoftClause,
+	improved func(Solution)) (Solution, error) {
+	numVars := s.NumVars()
+	for _, c := range soft {
+		for _, l := range c.Literals {
+			if int(l.Var()) >= numVars {
+				return unknown(), fmt.Errorf("Variable %v goes beyond the %d vars", l.Var(), numVars)
+			}
+		}
+	}
+	costOf := Problem{Soft: soft}
+	best := unknown()
+	var bestCost uint64
+	update := func(solution Solution) {
+		solution.Assignment = solution.Assignment[:numVars]
+		if cost := solution.Cost(costOf); best.Status != Sat || cost < bestCost {
+			best, bestCost = solution, cost
+			if improved != nil {
+				improved(solution)
+			}
+		}
+	}
+
+	solution, err := s.SolveContext(ctx)
+	if err != nil || solution.Status != Sat {
+		return solution, err
+	}
+	update(solution)
+	m := &maxSat{s: s, weights: make(map[Literal]uint64), sums: make(map[Literal]sum)}
+	for _, c := range soft {
+		m.addSoft(c)
+	}
+	// Only assume the soft clauses with the largest weights at first, to find
+	// good solutions sooner.
+	threshold := m.nextThreshold(math.MaxUint64)
+	for threshold > 0 && bestCost > m.lowerBound {
+		solution, err := s.SolveAssumingContext(ctx, m.assumptions(threshold))
+		if err != nil {
+			return best, err
+		}
+		if solution.Status == Sat {
+			update(solution)
+			threshold = m.nextThreshold(threshold)
+			continue
+		}
+		if len(solution.Failed) == 0 {
+			// Unsat regardless of the assumptions, though it was sat before.
+			return solution, nil
+		}
+		m.relax(solution.Failed)
+	}
+	return best, nil
+}
+
+// maxSat holds the soft constraints of the OLL algorithm, as assumptions with
+// weights. An assumption which is false costs its weight.
+type maxSat struct {
+	s          *Solver
+	literals   []Literal // assumptions in the order they were added
+	weights    map[Literal]uint64
+	sums       map[Literal]sum // assumptions that bound a sum
+	lowerBound uint64          // cost that every solution has
+}
+
+// sum is the outputs of a totalizer: outputs[k] is true if more than k of its
+// inputs are true. Assuming ¬outputs[bound] limits the sum to bound.
+type sum struct {
+	outputs []Literal
+	bound   int
+}
+
+// addSoft turns a soft clause into an assumption: its literal if it has one,
+// or else a new var which implies it.
+func (m *maxSat) addSoft(c SoftClause) {
+	switch {
+	case c.Weight == 0:
+	case c.Empty():
+		m.lowerBound += c.Weight
+	case len(c.Literals) == 1:
+		m.addWeight(c.Literals[0], c.Weight)
+	default:
+		b := Positive(m.s.NewVar())
+		literals := append([]Literal{b.Negate()}, c.Literals...)
+		m.s.AddClause(literals...)
+		m.addWeight(b, c.Weight)
+	}
+}
+
+func (m *maxSat) addWeight(l Literal, weight uint64) {
+	if _, ok := m.weights[l]; !ok {
+		m.literals = append(m.literals, l)
+	}
+	m.weights[l] += weight
+}
+
+// assumptions returns the assumptions with a weight of at least threshold.
+func (m *maxSat) assumptions(threshold uint64) []Literal {
+	var result []Literal
+	for _, l := range m.literals {
+		if m.weights[l] >= threshold {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// nextThreshold returns the largest weight below threshold, or 0 if none.
+func (m *maxSat) nextThreshold(threshold uint64) uint64 {
+	var next uint64
+	for _, l := range m.literals {
+		if w := m.weights[l]; w < threshold && w > next {
+			next = w
+		}
+	}
+	return next
+}
+
+// relax lowers the weights of a core of assumptions (which can't all be true)
+// by their minimum weight. That weight is added back by assuming the sum of
+// the false assumptions is at most one, since at least one is false.
+func (m *maxSat) relax(core []Literal) {
+	minWeight := uint64(math.MaxUint64)
+	for _, l := range core {
+		if m.weights[l] < minWeight {
+			minWeight = m.weights[l]
+		}
+	}
+	m.lowerBound += minWeight
+	falsified := make([]Literal, len(core))
+	for i, l := range core {
+		m.weights[l] -= minWeight
+		falsified[i] = l.Negate()
+		// A sum's bound was exceeded, so also try the next bound.
+		if s, ok := m.sums[l]; ok && s.bound+1 < len(s.outputs) {
+			m.addSum(sum{outputs: s.outputs, bound: s.bound + 1}, minWeight)
+		}
+	}
+	if len(falsified) > 1 {
+		m.addSum(sum{outputs: m.totalizer(falsified), bound: 1}, minWeight)
+	}
+}
+
+func (m *maxSat) addSum(s sum, weight uint64) {
+	l := s.outputs[s.bound].Negate()
+	m.sums[l] = s
+	m.addWeight(l, weight)
+}
+
+// totalizer returns outputs which count the true inputs, by merging the counts
+// of each half. Only the clauses which make outputs true are needed.
+func (m *maxSat) totalizer(inputs []Literal) []Literal {
+	if len(inputs) == 1 {
+		return inputs
+	}
+	left := m.totalizer(inputs[:len(inputs)/2])
+	right := m.totalizer(inputs[len(inputs)/2:])
+	outputs := make([]Literal, len(inputs))
+	for i := range outputs {
+		outputs[i] = Positive(m.s.NewVar())
+	}
+	for i := 0; i <= len(left); i++ {
+		for j := 0; j <= len(right); j++ {
+			if i+j == 0 {
+				continue
+			}
+			clause := []Literal{outputs[i+j-1]}
+			if i > 0 {
+				clause = append(clause, left[i-1].Negate())
+			}
+			if j > 0 {
+				clause = append(clause, right[j-1].Negate())
+			}
+			m.s.AddClause(clause...)
+		}
+	}
+	return outputs
+}
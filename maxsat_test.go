@@ -0,0 +1,121 @@
+package s1t
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// bruteForceCost returns the minimum cost of problem's soft clauses over the
+// assignments that satisfy its clauses, or false if there are none.
+func bruteForceCost(problem Problem) (uint64, bool) {
+	n := problem.Spec.NumVariables
+	found := false
+	var best uint64
+	for bits := 0; bits < 1<<uint(n); bits++ {
+		solution := Solution{Status: Sat, IsSat: true, Assignment: make([]int, n)}
+		for v := range solution.Assignment {
+			solution.Assignment[v] = (bits >> uint(v)) & 1
+		}
+		if ok, _ := solution.Satisfies(problem); !ok {
+			continue
+		}
+		if cost := solution.Cost(problem); !found || cost < best {
+			best, found = cost, true
+		}
+	}
+	return best, found
+}
+
+func TestMaxSat(t *testing.T) {
+	problem, err := ParseDimacs(strings.NewReader(strings.Join([]string{
+		"p wcnf 3 6 10",
+		"10 1 2 3 0",
+		"10 -1 -2 0",
+		"3 1 0",
+		"3 2 0",
+		"2 -3 0",
+		"1 1 3 0",
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var costs []uint64
+	solution, err := SolveMaxSat(problem, Options{}, func(s Solution) {
+		costs = append(costs, s.Cost(problem))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if solution.Status != Sat {
+		t.Fatalf("Expected sat, but got %v", solution)
+	}
+	if ok, c := solution.Satisfies(problem); !ok {
+		t.Errorf("Solution %v falsifies hard clause %v", solution, c)
+	}
+	// Either 1 or 2 is false, and 3 is true if 2 is.
+	if cost := solution.Cost(problem); cost != 3 {
+		t.Errorf("Expected cost 3, but got %d for %v", cost, solution)
+	}
+	for i := 1; i < len(costs); i++ {
+		if costs[i] >= costs[i-1] {
+			t.Errorf("Expected improving costs, but got %v", costs)
+		}
+	}
+	if len(costs) == 0 || costs[len(costs)-1] != 3 {
+		t.Errorf("Expected the last improvement to cost 3, but got %v", costs)
+	}
+}
+
+func TestMaxSatUnsatHard(t *testing.T) {
+	problem, err := ParseDimacs(strings.NewReader(strings.Join([]string{
+		"h 1 0",
+		"h -1 0",
+		"5 2 0",
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if solution, _ := SolveMaxSat(problem, Options{}, nil); solution.Status != Unsat {
+		t.Errorf("Expected unsat, but got %v", solution)
+	}
+}
+
+func TestMaxSatMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		problem := randomProblem(rng, 10, rng.Intn(20))
+		for j := 0; j < 5+rng.Intn(20); j++ {
+			var c Clause
+			for _, v := range rng.Perm(10)[:1+rng.Intn(3)] {
+				c.Literals = append(c.Literals, Literal(2*v+rng.Intn(2)))
+			}
+			weight := uint64(1 + rng.Intn(5))
+			if i%2 == 0 {
+				weight = 1
+			}
+			problem.Soft = append(problem.Soft, SoftClause{Clause: c, Weight: weight})
+		}
+		expected, sat := bruteForceCost(problem)
+		solution, err := SolveMaxSat(problem, Options{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sat {
+			if solution.Status != Unsat {
+				t.Errorf("Problem %d, expected unsat, but got %v", i, solution)
+			}
+			continue
+		}
+		if solution.Status != Sat {
+			t.Errorf("Problem %d, expected sat, but got %v", i, solution)
+			continue
+		}
+		if ok, c := solution.Satisfies(problem); !ok {
+			t.Errorf("Problem %d, solution falsifies hard clause %v", i, c)
+		}
+		if cost := solution.Cost(problem); cost != expected {
+			t.Errorf("Problem %d, expected cost %d, but got %d", i, expected, cost)
+		}
+	}
+}
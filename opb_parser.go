@@ -0,0 +1,147 @@
+package s1t
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseOPB parses a pseudo-Boolean problem in the OPB format, such as:
+//
+//	min: +1 x1 +2 x2 ;
+//	* a comment
+//	+3 x1 +2 x2 +1 ~x3 >= 4 ;
+//	+1 x2 -1 x3 = 0 ;
+//
+// Lines starting with "*" are comments, and each statement ends with ";".
+// Vars are named x1, x2 and so on, and literals may be negated by "~". Each
+// constraint compares a sum of terms with ">=", "<=" or "=" to a bound, and
+// the optional "min:" statement gives an objective to minimize. Nonlinear
+// terms (products of literals) are not supported.
+func ParseOPB(in io.Reader) (Problem, error) {
+	s := bufio.NewScanner(in)
+	numVars := 0
+	var text strings.Builder
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "*") {
+			// The first comment usually declares the number of vars.
+			fields := strings.Fields(line)
+			for i, f := range fields {
+				if f == "#variable=" && i+1 < len(fields) {
+					if num, err := strconv.Atoi(fields[i+1]); err == nil && num > numVars {
+						numVars = num
+					}
+				}
+			}
+			continue
+		}
+		text.WriteString(line)
+		text.WriteString("\n")
+	}
+	if err := s.Err(); err != nil {
+		return Problem{}, err
+	}
+	statements := strings.Split(text.String(), ";")
+	if last := strings.TrimSpace(statements[len(statements)-1]); last != "" {
+		return Problem{}, fmt.Errorf("Expected \";\" after the statement %q", last)
+	}
+	var p Problem
+	// Like "#constraint=", an equality counts as one constraint.
+	numConstraints := 0
+	for _, statement := range statements[:len(statements)-1] {
+		fields := strings.Fields(statement)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "min:" {
+			if p.Objective != nil {
+				return Problem{}, fmt.Errorf("Expected at most one objective, but got %q", statement)
+			}
+			terms, err := parseOPBTerms(fields[1:], &numVars)
+			if err != nil {
+				return Problem{}, err
+			}
+			p.Objective = append([]PBTerm{}, terms...)
+			continue
+		}
+		constraints, err := parseOPBConstraint(fields, &numVars)
+		if err != nil {
+			return Problem{}, err
+		}
+		p.Constraints = append(p.Constraints, constraints...)
+		numConstraints++
+	}
+	p.Spec = ProblemSpec{Format: "opb", NumVariables: numVars, NumClauses: numConstraints}
+	p.Symbols = NewSymbolTable()
+	for v := 1; v <= numVars; v++ {
+		p.Symbols.Intern("x" + strconv.Itoa(v))
+	}
+	return p, nil
+}
+
+// parseOPBConstraint parses the terms, comparison and bound of a constraint,
+// as constraints with ">=": one for ">=" or "<=", and two for "=".
+func parseOPBConstraint(fields []string, numVars *int) ([]PBConstraint, error) {
+	statement := strings.Join(fields, " ")
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("Expected a comparison and a bound in constraint %q", statement)
+	}
+	op := fields[len(fields)-2]
+	bound, err := strconv.ParseInt(fields[len(fields)-1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse bound %q in constraint %q %e",
+			fields[len(fields)-1], statement, err)
+	}
+	terms, err := parseOPBTerms(fields[:len(fields)-2], numVars)
+	if err != nil {
+		return nil, err
+	}
+	atLeast := PBConstraint{Terms: terms, Bound: bound}
+	// a <= b is -a >= -b.
+	atMost := PBConstraint{Bound: -bound}
+	for _, t := range terms {
+		atMost.Terms = append(atMost.Terms, PBTerm{Coefficient: -t.Coefficient, Literal: t.Literal})
+	}
+	switch op {
+	case ">=":
+		return []PBConstraint{atLeast}, nil
+	case "<=":
+		return []PBConstraint{atMost}, nil
+	case "=":
+		return []PBConstraint{atLeast, atMost}, nil
+	}
+	return nil, fmt.Errorf("Expected \">=\", \"<=\" or \"=\" before the bound in constraint %q", statement)
+}
+
+// parseOPBTerms parses pairs of a coefficient and a literal. If a literal
+// goes beyond numVars, numVars grows to fit.
+func parseOPBTerms(fields []string, numVars *int) ([]PBTerm, error) {
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("Expected terms of a coefficient and one literal (products are not supported): %q",
+			strings.Join(fields, " "))
+	}
+	var terms []PBTerm
+	for i := 0; i < len(fields); i += 2 {
+		coefficient, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse coefficient %q %e", fields[i], err)
+		}
+		name := strings.TrimPrefix(fields[i+1], "~")
+		num, err := strconv.Atoi(strings.TrimPrefix(name, "x"))
+		if !strings.HasPrefix(name, "x") || err != nil || num < 1 {
+			return nil, fmt.Errorf("Expected a literal like x1 or ~x1, but got %q", fields[i+1])
+		}
+		if num > *numVars {
+			*numVars = num
+		}
+		l := Positive(VarNum(num - 1))
+		if name != fields[i+1] {
+			l = l.Negate()
+		}
+		terms = append(terms, PBTerm{Coefficient: coefficient, Literal: l})
+	}
+	return terms, nil
+}
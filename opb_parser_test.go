@@ -0,0 +1,70 @@
+package s1t
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseOPB(t *testing.T) {
+	problem, err := ParseOPB(strings.NewReader(strings.Join([]string{
+		"* #variable= 4 #constraint= 3",
+		"min: +1 x2 -1 x3 ;",
+		"+3 x1 +2 x2",
+		"  +1 ~x3 >= 4 ;",
+		"* an equality",
+		"+1 x1 +1 x2 = 1 ; -1 x1 <= -1 ;",
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x1, x2, x3 := Positive(0), Positive(1), Positive(2)
+	expected := Problem{
+		Spec: ProblemSpec{Format: "opb", NumVariables: 4, NumClauses: 3},
+		Constraints: []PBConstraint{
+			{Terms: []PBTerm{{3, x1}, {2, x2}, {1, x3.Negate()}}, Bound: 4},
+			{Terms: []PBTerm{{1, x1}, {1, x2}}, Bound: 1},
+			{Terms: []PBTerm{{-1, x1}, {-1, x2}}, Bound: -1},
+			{Terms: []PBTerm{{1, x1}}, Bound: 1},
+		},
+		Objective: []PBTerm{{1, x2}, {-1, x3}},
+	}
+	if diff := cmp.Diff(expected, problem, cmp.AllowUnexported(SymbolTable{}),
+		cmp.FilterPath(func(p cmp.Path) bool { return p.String() == "Symbols" }, cmp.Ignore())); diff != "" {
+		t.Errorf("ParseOPB differs (-want +got):\n%s", diff)
+	}
+	if name := problem.Symbols.Name(3); name != "x4" {
+		t.Errorf("Expected x4 to name the last var, but got %q", name)
+	}
+	solution, err := Minimize(problem, Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// x1 is true, so x2 is false for the equality, and then 3 + ¬x3 >= 4.
+	if expected := []int{1, 0, 0}; !solution.IsSat || !cmp.Equal(expected, solution.Assignment[:3]) {
+		t.Errorf("Expected %v, but got %v", expected, solution)
+	}
+	if !strings.Contains(solution.Output(problem), "v ~x3\n") {
+		t.Errorf("Expected named output, but got %q", solution.Output(problem))
+	}
+}
+
+func TestParseOPBErrorCases(t *testing.T) {
+	cases := []string{
+		"+1 x1 >= 1",
+		"+1 x1 x2 >= 1 ;",
+		"x1 >= 1 ;",
+		"+1 y1 >= 1 ;",
+		"+1 x0 >= 1 ;",
+		"+1 x1 > 1 ;",
+		"+1 x1 >= a ;",
+		">= ;",
+		"min: +1 x1 ; min: +1 x2 ;",
+	}
+	for _, c := range cases {
+		if _, err := ParseOPB(strings.NewReader(c)); err == nil {
+			t.Errorf("Expected an error for %q", c)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Pseudo-Boolean constraints, propagated natively by counting the slack of
+// each constraint: the coefficients of its literals which are not false, minus
+// its bound. A literal whose coefficient is more than the slack must be true.
+// Each propagation (or conflict) is explained by a clause implied by the
+// constraint, so conflict analysis only ever sees clauses. Propagations are
+// only explained when conflict analysis needs their reason.
+
+package s1t
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// pbConstraint is a normalized PBConstraint: the terms have positive
+// coefficients of at most the bound, in decreasing order.
+type pbConstraint struct {
+	terms []PBTerm
+	bound int64
+	// slack is the sum of the coefficients of the literals which are not false,
+	// counting up to the propagated part of the trail, minus the bound.
+	slack int64
+}
+
+// pbOccurrence is a term of a constraint, found by its literal.
+type pbOccurrence struct {
+	constraint  int
+	coefficient int64
+}
+
+type pbConstraints struct {
+	constraints []pbConstraint
+	occurs      [][]pbOccurrence // by literal
+	// reason is by var: the constraint which implied it, if its reason is
+	// constraintReason.
+	reason []int
+}
+
+func newPBConstraints(numVars int) pbConstraints {
+	return pbConstraints{
+		occurs: make([][]pbOccurrence, 2*numVars),
+		reason: make([]int, numVars),
+	}
+}
+
+// normalize returns the terms and bound of an equivalent constraint, with
+// positive coefficients of at most the bound, at most one term per var, and
+// the terms in decreasing order of coefficient. The bound is not positive if
+// the constraint is always true.
+func normalize(c PBConstraint) ([]PBTerm, int64) {
+	bound := c.Bound
+	coefficients := make(map[VarNum]int64)
+	var vars []VarNum
+	for _, t := range c.Terms {
+		v := t.Literal.Var()
+		if _, ok := coefficients[v]; !ok {
+			vars = append(vars, v)
+		}
+		if t.Literal.AsInt() == 1 {
+			coefficients[v] += t.Coefficient
+		} else {
+			// a¬x = a - ax
+			coefficients[v] -= t.Coefficient
+			bound -= t.Coefficient
+		}
+	}
+	var terms []PBTerm
+	for _, v := range vars {
+		a := coefficients[v]
+		switch {
+		case a > 0:
+			terms = append(terms, PBTerm{Coefficient: a, Literal: Positive(v)})
+		case a < 0:
+			// ax = a + |a|¬x
+			terms = append(terms, PBTerm{Coefficient: -a, Literal: Negative(v)})
+			bound -= a
+		}
+	}
+	if bound <= 0 {
+		return nil, bound
+	}
+	for i := range terms {
+		if terms[i].Coefficient > bound {
+			terms[i].Coefficient = bound
+		}
+	}
+	sort.SliceStable(terms, func(i, j int) bool {
+		return terms[i].Coefficient > terms[j].Coefficient
+	})
+	return terms, bound
+}
+
+// constraint returns the pseudo-Boolean constraint that at least
+// len(Literals) - K of the literals are false, which the solver propagates by
+// counting like any other.
+func (a AtMost) constraint() PBConstraint {
+	c := PBConstraint{Bound: int64(len(a.Literals) - a.K)}
+	for _, l := range a.Literals {
+		c.Terms = append(c.Terms, PBTerm{Coefficient: 1, Literal: l.Negate()})
+	}
+	return c
+}
+
+// AddAtMost adds a cardinality constraint to the problem, like AddClause.
+// Returns an error if a literal's variable does not exist.
+func (s *Solver) AddAtMost(a AtMost) error {
+	return s.AddConstraint(a.constraint())
+}
+
+// AddConstraint adds a pseudo-Boolean constraint to the problem, like
+// AddClause. Returns an error if a literal's variable does not exist.
+func (s *Solver) AddConstraint(c PBConstraint) error {
+	for _, t := range c.Terms {
+		if int(t.Literal.Var()) >= s.NumVars() {
+			return fmt.Errorf("Variable %v goes beyond the %d vars", t.Literal.Var(), s.NumVars())
+		}
+	}
+	for _, t := range c.Terms {
+		s.restore([]Literal{t.Literal})
+	}
+	if !s.toLevelZero() {
+		return nil
+	}
+	ci := s.addConstraint(c)
+	if ci == none {
+		return nil
+	}
+	if conflict := s.checkPB(ci); conflict != noReason {
+		s.setUnsat(conflict)
+	} else if conflict := s.propagate(); conflict != noReason {
+		s.setUnsat(conflict)
+	}
+	return nil
+}
+
+// addConstraint stores a constraint, with its slack at the current (fully
+// propagated) assignment, and returns its index, or none if it is always true.
+func (s *Solver) addConstraint(c PBConstraint) int {
+	terms, bound := normalize(c)
+	if bound <= 0 {
+		return none
+	}
+	ci := len(s.pb.constraints)
+	slack := -bound
+	for _, t := range terms {
+		if !s.isFalse(t.Literal) {
+			slack += t.Coefficient
+		}
+		s.pb.occurs[t.Literal] = append(s.pb.occurs[t.Literal],
+			pbOccurrence{constraint: ci, coefficient: t.Coefficient})
+	}
+	s.pb.constraints = append(s.pb.constraints, pbConstraint{terms: terms, bound: bound, slack: slack})
+	return ci
+}
+
+// propagatePB lowers the slack of the constraints with ¬l now that l is true,
+// and enqueues the literals they need. All the slacks are updated, even if
+// there is a conflict, so that backjump can restore them.
+// Returns the falsified clause if there is a conflict, or noReason.
+func (s *Solver) propagatePB(l Literal) ClauseNum {
+	occurs := s.pb.occurs[l.Negate()]
+	for _, o := range occurs {
+		s.pb.constraints[o.constraint].slack -= o.coefficient
+	}
+	for _, o := range occurs {
+		if conflict := s.checkPB(o.constraint); conflict != noReason {
+			return conflict
+		}
+	}
+	return noReason
+}
+
+// unassignedPB restores the slack of the constraints with ¬l, when l was
+// propagated and is unassigned by a backjump.
+func (s *Solver) unassignedPB(l Literal) {
+	for _, o := range s.pb.occurs[l.Negate()] {
+		s.pb.constraints[o.constraint].slack += o.coefficient
+	}
+}
+
+// checkPB enqueues the unassigned literals of constraint ci whose coefficients
+// are more than its slack. Returns a falsified clause if the slack is negative,
+// or noReason.
+func (s *Solver) checkPB(ci int) ClauseNum {
+	c := &s.pb.constraints[ci]
+	if c.slack < 0 {
+		return s.explainPB(c, Literal(none), s.trail.Len())
+	}
+	for _, t := range c.terms {
+		if t.Coefficient <= c.slack {
+			break
+		}
+		v := t.Literal.Var()
+		if s.trail.assignments[v] != none {
+			continue
+		}
+		if s.trail.DecisionLevel() == 0 {
+			// Level 0 reasons are kept, e.g., for cores.
+			s.enqueue(t.Literal, s.explainPB(c, t.Literal, s.trail.Len()))
+		} else {
+			s.pb.reason[v] = ci
+			s.enqueue(t.Literal, constraintReason)
+		}
+	}
+	return noReason
+}
+
+// explainPB adds a clause implied by the constraint, of l and the constraint's
+// literals which were false before index end of the trail, as the reason to
+// propagate l. With l of none, the clause is falsified, to explain a conflict.
+func (s *Solver) explainPB(c *pbConstraint, l Literal, end int) ClauseNum {
+	var literals []Literal
+	if l != Literal(none) {
+		literals = append(literals, l)
+	}
+	for _, t := range c.terms {
+		if s.isFalse(t.Literal) && s.trail.index[t.Literal.Var()] < end {
+			literals = append(literals, t.Literal)
+		}
+	}
+	return s.addExplanation(literals)
+}
+
+// reasonOf returns the clause which forced v, first explaining it if a
+// constraint implied it.
+func (s *Solver) reasonOf(v VarNum) ClauseNum {
+	if s.trail.reason[v] == constraintReason {
+		c := &s.pb.constraints[s.pb.reason[v]]
+		l := Literal(2*int(v) + s.trail.assignments[v])
+		s.trail.reason[v] = s.explainPB(c, l, s.trail.index[v])
+	}
+	return s.trail.reason[v]
+}
+
+// Minimize returns a solution of problem's clauses and constraints which
+// minimizes problem.Objective (see Solution.Objective), or an Unsat solution.
+// It calls improved with each better solution found.
+func Minimize(problem Problem, opts Options, improved func(Solution)) (Solution, error) {
+	return NewSolver(problem, opts).Minimize(problem.Objective, improved)
+}
+
+// Minimize is like the Minimize function, but for the solver's problem. It
+// searches linearly: after each solution, it adds a constraint that the next
+// has a smaller objective, so those constraints remain afterwards.
+func (s *Solver) Minimize(objective []PBTerm, improved func(Solution)) (Solution, error) {
+	return s.MinimizeContext(context.Background(), objective, improved)
+}
+
+// MinimizeContext is like Minimize, but if ctx is done (or a budget is
+// exhausted) first, it gives up with the error and the best solution found so
+// far: Sat but maybe not optimal, or Unknown if there is none.
+func (s *Solver) MinimizeContext(ctx context.Context, objective []PBTerm,
+	improved func(Solution)) (Solution, error) {
+	for _, t := range objective {
+		if int(t.Literal.Var()) >= s.NumVars() {
+			return unknown(), fmt.Errorf("Variable %v goes beyond the %d vars", t.Literal.Var(), s.NumVars())
+		}
+	}
+	valueOf := Problem{Objective: objective}
+	best := unknown()
+	for {
+		solution, err := s.SolveContext(ctx)
+		if err != nil {
+			return best, err
+		}
+		if solution.Status != Sat {
+			if best.Status == Sat {
+				return best, nil
+			}
+			return solution, nil
+		}
+		best = solution
+		if improved != nil {
+			improved(solution)
+		}
+		// The objective must be at most value - 1, so its negation at least
+		// 1 - value.
+		better := PBConstraint{Bound: 1 - solution.Objective(valueOf)}
+		for _, t := range objective {
+			better.Terms = append(better.Terms, PBTerm{Coefficient: -t.Coefficient, Literal: t.Literal})
+		}
+		if err := s.AddConstraint(better); err != nil {
+			return best, err
+		}
+	}
+}
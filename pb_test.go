@@ -0,0 +1,330 @@
+package s1t
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalize(t *testing.T) {
+	x, y, z := Positive(0), Positive(1), Positive(2)
+	cases := []struct {
+		c             PBConstraint
+		expectedTerms []PBTerm
+		expectedBound int64
+	}{
+		{PBConstraint{Terms: []PBTerm{{1, x}, {3, y}, {2, z}}, Bound: 4},
+			[]PBTerm{{3, y}, {2, z}, {1, x}}, 4},
+		// -2x >= -1 is 2¬x >= 1, saturated to ¬x >= 1.
+		{PBConstraint{Terms: []PBTerm{{-2, x}}, Bound: -1},
+			[]PBTerm{{1, x.Negate()}}, 1},
+		// 2¬x + 3y >= 3 is -2x + 3y >= 1, and x + 2¬x is 2 - x.
+		{PBConstraint{Terms: []PBTerm{{2, x.Negate()}, {3, y}}, Bound: 3},
+			[]PBTerm{{3, y}, {2, x.Negate()}}, 3},
+		{PBConstraint{Terms: []PBTerm{{1, x}, {2, x.Negate()}, {1, y}}, Bound: 2},
+			[]PBTerm{{1, x.Negate()}, {1, y}}, 1},
+		{PBConstraint{Terms: []PBTerm{{0, x}}, Bound: 1}, nil, 1},
+		// Always true.
+		{PBConstraint{Terms: []PBTerm{{1, x}, {-1, y}}, Bound: -1}, nil, 0},
+		{PBConstraint{Terms: []PBTerm{{5, x}, {-5, x}}, Bound: 0}, nil, 0},
+	}
+	for _, c := range cases {
+		terms, bound := normalize(c.c)
+		if diff := cmp.Diff(c.expectedTerms, terms); diff != "" {
+			t.Errorf("normalize(%v) terms differ (-want +got):\n%s", c.c, diff)
+		}
+		if c.expectedBound > 0 && bound != c.expectedBound || c.expectedBound == 0 && bound > 0 {
+			t.Errorf("normalize(%v) expected bound %d, but got %d", c.c, c.expectedBound, bound)
+		}
+	}
+}
+
+// randomPBProblem returns a problem with random constraints (with some
+// negative coefficients) along with random clauses.
+func randomPBProblem(rng *rand.Rand, numVars, numClauses, numConstraints int) Problem {
+	problem := randomProblem(rng, numVars, numClauses)
+	for i := 0; i < numConstraints; i++ {
+		var c PBConstraint
+		for _, v := range rng.Perm(numVars)[:2+rng.Intn(numVars-1)] {
+			l := Positive(VarNum(v))
+			if rng.Intn(2) == 0 {
+				l = l.Negate()
+			}
+			c.Terms = append(c.Terms, PBTerm{Coefficient: int64(rng.Intn(9) - 2), Literal: l})
+		}
+		c.Bound = int64(rng.Intn(12) - 2)
+		problem.Constraints = append(problem.Constraints, c)
+	}
+	return problem
+}
+
+// bruteForcePB returns the minimum objective over the assignments that satisfy
+// the problem's clauses and constraints, or false if there are none.
+func bruteForcePB(problem Problem) (int64, bool) {
+	n := problem.Spec.NumVariables
+	found := false
+	var best int64
+	for bits := 0; bits < 1<<uint(n); bits++ {
+		solution := Solution{Status: Sat, IsSat: true, Assignment: make([]int, n)}
+		for v := range solution.Assignment {
+			solution.Assignment[v] = (bits >> uint(v)) & 1
+		}
+		if ok, _ := solution.Satisfies(problem); !ok {
+			continue
+		}
+		if ok, _ := solution.SatisfiesConstraints(problem); !ok {
+			continue
+		}
+		if value := solution.Objective(problem); !found || value < best {
+			best, found = value, true
+		}
+	}
+	return best, found
+}
+
+func TestPBConstraints(t *testing.T) {
+	rng := rand.New(rand.NewSource(21))
+	for i := 0; i < 300; i++ {
+		problem := randomPBProblem(rng, 8, rng.Intn(12), 1+rng.Intn(5))
+		_, expectSat := bruteForcePB(problem)
+		for _, opts := range []Options{{}, {Core: true, ReduceInterval: 1}} {
+			solution, err := NewSolver(problem, opts).Solve()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if solution.IsSat != expectSat {
+				t.Fatalf("Expected sat %v, but got %v for %+v", expectSat, solution, problem)
+			}
+			if !expectSat {
+				continue
+			}
+			if ok, c := solution.Satisfies(problem); !ok {
+				t.Errorf("Solution %v falsifies clause %v", solution, c)
+			}
+			if ok, c := solution.SatisfiesConstraints(problem); !ok {
+				t.Errorf("Solution %v falsifies constraint %v", solution, c)
+			}
+		}
+	}
+}
+
+func TestPBPropagatesWithoutClauses(t *testing.T) {
+	// At most 2 of 10 vars, and at least 2 of the first 3: the rest are false.
+	problem := Problem{Spec: ProblemSpec{NumVariables: 10}}
+	atMost := PBConstraint{Bound: -2}
+	for v := 0; v < 10; v++ {
+		atMost.Terms = append(atMost.Terms, PBTerm{Coefficient: -1, Literal: Positive(VarNum(v))})
+	}
+	atLeast := PBConstraint{Bound: 2}
+	for v := 0; v < 3; v++ {
+		atLeast.Terms = append(atLeast.Terms, PBTerm{Coefficient: 1, Literal: Positive(VarNum(v))})
+	}
+	problem.Constraints = []PBConstraint{atMost, atLeast}
+	solution := Solve(problem)
+	if !solution.IsSat {
+		t.Fatalf("Expected sat, but got %v", solution)
+	}
+	if ok, c := solution.SatisfiesConstraints(problem); !ok {
+		t.Errorf("Solution %v falsifies constraint %v", solution, c)
+	}
+	for v := 3; v < 10; v++ {
+		if solution.Assignment[v] != 0 {
+			t.Errorf("Expected v%d false, but got %v", v, solution.Assignment)
+		}
+	}
+}
+
+func TestAddConstraint(t *testing.T) {
+	x, y := Positive(0), Positive(1)
+	s := NewSolver(Problem{Spec: ProblemSpec{NumVariables: 2}}, Options{})
+	// x + y >= 1
+	if err := s.AddConstraint(PBConstraint{Terms: []PBTerm{{1, x}, {1, y}}, Bound: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if solution, _ := s.Solve(); !solution.IsSat {
+		t.Fatalf("Expected sat, but got %v", solution)
+	}
+	// 2x + y <= 0 makes both false.
+	if err := s.AddConstraint(PBConstraint{Terms: []PBTerm{{-2, x}, {-1, y}}, Bound: 0}); err != nil {
+		t.Fatal(err)
+	}
+	if solution, _ := s.Solve(); solution.Status != Unsat {
+		t.Errorf("Expected unsat, but got %v", solution)
+	}
+	if err := s.AddConstraint(PBConstraint{Terms: []PBTerm{{1, Positive(2)}}, Bound: 1}); err == nil {
+		t.Errorf("Expected an error for a var beyond the solver's vars")
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	rng := rand.New(rand.NewSource(210))
+	for i := 0; i < 200; i++ {
+		problem := randomPBProblem(rng, 7, rng.Intn(8), rng.Intn(3))
+		for v := 0; v < 7; v++ {
+			if rng.Intn(3) > 0 {
+				problem.Objective = append(problem.Objective,
+					PBTerm{Coefficient: int64(rng.Intn(11) - 3), Literal: Positive(VarNum(v))})
+			}
+		}
+		expected, expectSat := bruteForcePB(problem)
+		var values []int64
+		solution, err := Minimize(problem, Options{}, func(s Solution) {
+			values = append(values, s.Objective(problem))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if solution.IsSat != expectSat {
+			t.Fatalf("Expected sat %v, but got %v for %+v", expectSat, solution, problem)
+		}
+		if !expectSat {
+			continue
+		}
+		if ok, c := solution.SatisfiesConstraints(problem); !ok {
+			t.Errorf("Solution %v falsifies constraint %v", solution, c)
+		}
+		if value := solution.Objective(problem); value != expected {
+			t.Errorf("Expected objective %d, but got %d for %+v", expected, value, problem)
+		}
+		for j := 1; j < len(values); j++ {
+			if values[j] >= values[j-1] {
+				t.Errorf("Expected improving objectives, but got %v", values)
+			}
+		}
+	}
+}
+
+func TestAtMost(t *testing.T) {
+	rng := rand.New(rand.NewSource(23))
+	for i := 0; i < 300; i++ {
+		problem := randomProblem(rng, 8, rng.Intn(16))
+		for j := 1 + rng.Intn(4); j > 0; j-- {
+			var a AtMost
+			for _, v := range rng.Perm(8)[:1+rng.Intn(7)] {
+				l := Positive(VarNum(v))
+				if rng.Intn(2) == 0 {
+					l = l.Negate()
+				}
+				a.Literals = append(a.Literals, l)
+			}
+			a.K = rng.Intn(len(a.Literals)+1) - 1
+			problem.AtMosts = append(problem.AtMosts, a)
+		}
+		expectSat := false
+		for bits := 0; bits < 1<<8 && !expectSat; bits++ {
+			solution := Solution{Status: Sat, IsSat: true, Assignment: make([]int, 8)}
+			for v := range solution.Assignment {
+				solution.Assignment[v] = (bits >> uint(v)) & 1
+			}
+			ok, _ := solution.Satisfies(problem)
+			okAtMosts, _ := solution.SatisfiesAtMosts(problem)
+			expectSat = ok && okAtMosts
+		}
+		solution := Solve(problem)
+		if solution.IsSat != expectSat {
+			t.Fatalf("Expected sat %v, but got %v for %+v", expectSat, solution, problem)
+		}
+		if !expectSat {
+			continue
+		}
+		if ok, c := solution.Satisfies(problem); !ok {
+			t.Errorf("Solution %v falsifies clause %v", solution, c)
+		}
+		if ok, a := solution.SatisfiesAtMosts(problem); !ok {
+			t.Errorf("Solution %v falsifies cardinality constraint %v", solution, a)
+		}
+	}
+}
+
+// pigeonHoleAtMosts returns n+1 pigeons in n holes, with a cardinality
+// constraint for each hole.
+func pigeonHoleAtMosts(n int) Problem {
+	pigeonIn := func(p, h int) Literal { return Positive(VarNum(p*n + h)) }
+	problem := Problem{Spec: ProblemSpec{Format: "cnf", NumVariables: n * (n + 1)}}
+	for p := 0; p <= n; p++ {
+		var c Clause
+		for h := 0; h < n; h++ {
+			c.Literals = append(c.Literals, pigeonIn(p, h))
+		}
+		problem.Clauses = append(problem.Clauses, c)
+	}
+	for h := 0; h < n; h++ {
+		a := AtMost{K: 1}
+		for p := 0; p <= n; p++ {
+			a.Literals = append(a.Literals, pigeonIn(p, h))
+		}
+		problem.AtMosts = append(problem.AtMosts, a)
+	}
+	problem.Spec.NumClauses = len(problem.Clauses) + len(problem.AtMosts)
+	return problem
+}
+
+func TestExplanationsAreFreed(t *testing.T) {
+	problem := pigeonHoleAtMosts(6)
+	var proof bytes.Buffer
+	s := NewSolver(problem, Options{Proof: &proof, ReduceInterval: 20})
+	if solution, err := s.Solve(); err != nil || solution.Status != Unsat {
+		t.Fatalf("Expected unsat, but got %v, %v", solution, err)
+	}
+	// Only the reasons at level 0 and the final conflict remain.
+	explanations := 0
+	for _, explanation := range s.learned.explanation {
+		if explanation {
+			explanations++
+		}
+	}
+	if explanations > s.trail.Len()+1 {
+		t.Errorf("Expected at most %d explanation clauses, but got %d", s.trail.Len()+1, explanations)
+	}
+	// Explanations are neither added to nor deleted from the proof.
+	added := make(map[string]bool)
+	for _, c := range problem.Clauses {
+		added[fmt.Sprint(c.Literals)] = true
+	}
+	for _, line := range strings.Split(strings.TrimSpace(proof.String()), "\n") {
+		fields := strings.Fields(line)
+		isDelete := fields[0] == "d"
+		if isDelete {
+			fields = fields[1:]
+		}
+		var literals []Literal
+		for _, f := range fields[:len(fields)-1] {
+			d, err := strconv.Atoi(f)
+			if err != nil {
+				t.Fatalf("Bad proof line %q: %v", line, err)
+			}
+			literals = append(literals, FromDimacs(d))
+		}
+		if key := fmt.Sprint(literals); !isDelete {
+			added[key] = true
+		} else if !added[key] {
+			t.Errorf("Proof deletes clause %v, which was not added", literals)
+		}
+	}
+}
+
+func TestAddAtMost(t *testing.T) {
+	literals := []Literal{Positive(0), Positive(1), Positive(2)}
+	s := NewSolver(Problem{Spec: ProblemSpec{NumVariables: 3}}, Options{})
+	for _, l := range literals[:2] {
+		s.AddClause(l)
+	}
+	if err := s.AddAtMost(AtMost{Literals: literals, K: 2}); err != nil {
+		t.Fatal(err)
+	}
+	solution, _ := s.Solve()
+	if !solution.IsSat || solution.Assignment[2] != 0 {
+		t.Fatalf("Expected sat with v2 false, but got %v", solution)
+	}
+	if err := s.AddAtMost(AtMost{Literals: literals, K: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if solution, _ := s.Solve(); solution.Status != Unsat {
+		t.Errorf("Expected unsat, but got %v", solution)
+	}
+}
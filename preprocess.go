@@ -0,0 +1,724 @@
+// Preprocessing before the search, in the style of SatELite: strengthening by
+// unit clauses, subsumption, self-subsuming resolution and bounded variable
+// elimination, along with substitution of equivalent literals and blocked
+// clause elimination. Removed clauses are kept on a reconstruction stack with
+// the var whose value satisfies them, to extend solutions of the simplified
+// clauses to the original ones, or to restore the clauses if the var is used
+// again.
+
+package s1t
+
+import (
+	"sort"
+)
+
+const (
+	// maxResolventSize limits the resolvents of an eliminated var.
+	maxResolventSize = 20
+	// maxEliminationOccurrences limits the clauses of each polarity of a var to
+	// eliminate, since it tries every pair.
+	maxEliminationOccurrences = 16
+	// maxBlockedOccurrences limits the clauses with the negated literal when
+	// checking if clauses with a literal are blocked.
+	maxBlockedOccurrences = 16
+	// maxPreprocessRounds limits the rounds of simplification.
+	maxPreprocessRounds = 5
+)
+
+// elimination is clauses removed from the problem, which v's value satisfies
+// if their other literals do not: those of a var eliminated by resolution or
+// substituted by an equivalent literal, or a clause blocked on v's literal.
+type elimination struct {
+	v       VarNum
+	clauses []Clause
+}
+
+// reconstruction is the stack of removed clauses, in the order they were
+// removed.
+type reconstruction struct {
+	stack   []elimination
+	stacked []bool // by var: has clauses on the stack
+}
+
+// extend assigns the vars of the stack so that their clauses are satisfied,
+// from the last removed, since its clauses only have vars which remain or
+// were removed later.
+func (r *reconstruction) extend(assignment []int) {
+	for i := len(r.stack) - 1; i >= 0; i-- {
+		e := r.stack[i]
+		if assignment[e.v] == none {
+			assignment[e.v] = 0
+		}
+		for _, c := range e.clauses {
+			satisfied := false
+			var own Literal
+			for _, l := range c.Literals {
+				if l.Var() == e.v {
+					own = l
+				}
+				if assignment[l.Var()] == l.AsInt() {
+					satisfied = true
+				}
+			}
+			// The other literals of the clauses with v and those with ¬v are
+			// not all false (the resolvents are satisfied, or not blocked), so
+			// v is never flipped back.
+			if !satisfied {
+				assignment[e.v] = own.AsInt()
+			}
+		}
+	}
+}
+
+type preprocessor struct {
+	clauses []Clause
+	deleted []bool
+	occurs  [][]int // by literal: the clauses which have it
+	frozen  []bool  // by var: vars not to eliminate
+	// eliminated is by var: eliminated by resolution or substitution.
+	eliminated []bool
+	r          reconstruction
+	proof      *proofWriter
+	// Scratch space for subset checks: marks[l] == stamp if l is marked.
+	marks []int
+	stamp int
+	// Clauses to check for subsumption, and unit clauses to propagate.
+	queue  []int
+	queued []bool // by clause
+	units  []int
+	unsat  bool
+	stats  *Statistics
+}
+
+// preprocess simplifies the clauses over numVars vars, without eliminating the
+// frozen vars. The simplified clauses are satisfiable if and only if the
+// clauses are, and the reconstruction extends their solutions. Changes are
+// written to the proof, if any.
+func preprocess(clauses []Clause, numVars int, frozen []bool, proof *proofWriter,
+	stats *Statistics) ([]Clause, reconstruction) {
+	p := newPreprocessor(clauses, numVars, frozen, proof, stats)
+	p.propagateUnits()
+	for round := 0; round < maxPreprocessRounds && !p.unsat; round++ {
+		substituted := p.substituteEquivalences()
+		p.subsumeAll()
+		blocked := !p.unsat && p.eliminateBlocked()
+		if !p.unsat && !p.eliminateAll() && !substituted && !blocked {
+			break
+		}
+	}
+	return p.result(), p.r
+}
+
+func newPreprocessor(clauses []Clause, numVars int, frozen []bool, proof *proofWriter,
+	stats *Statistics) *preprocessor {
+	p := &preprocessor{
+		occurs:     make([][]int, 2*numVars),
+		frozen:     frozen,
+		eliminated: make([]bool, numVars),
+		r:          reconstruction{stacked: make([]bool, numVars)},
+		proof:      proof,
+		marks:      make([]int, 2*numVars),
+		stats:      stats,
+	}
+	for _, c := range clauses {
+		literals := withoutDuplicates(c.Literals)
+		if isTautology(literals) {
+			if proof != nil {
+				proof.delete(c.Literals)
+			}
+			continue
+		}
+		p.add(literals)
+	}
+	return p
+}
+
+// result returns the clauses which remain.
+func (p *preprocessor) result() []Clause {
+	if p.unsat {
+		return []Clause{{}}
+	}
+	var result []Clause
+	for ci, c := range p.clauses {
+		if !p.deleted[ci] {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func isTautology(literals []Literal) bool {
+	seen := make(map[Literal]bool, len(literals))
+	for _, l := range literals {
+		if seen[l.Negate()] {
+			return true
+		}
+		seen[l] = true
+	}
+	return false
+}
+
+// add adds a clause, to check for subsumption and, if unit, to propagate.
+func (p *preprocessor) add(literals []Literal) {
+	ci := len(p.clauses)
+	p.clauses = append(p.clauses, Clause{Literals: literals})
+	p.deleted = append(p.deleted, false)
+	p.queued = append(p.queued, false)
+	for _, l := range literals {
+		p.occurs[l] = append(p.occurs[l], ci)
+	}
+	p.enqueue(ci)
+}
+
+func (p *preprocessor) enqueue(ci int) {
+	switch len(p.clauses[ci].Literals) {
+	case 0:
+		p.unsat = true
+	case 1:
+		p.units = append(p.units, ci)
+	}
+	if !p.queued[ci] {
+		p.queued[ci] = true
+		p.queue = append(p.queue, ci)
+	}
+}
+
+func (p *preprocessor) delete(ci int) {
+	if p.proof != nil {
+		p.proof.delete(p.clauses[ci].Literals)
+	}
+	p.deleted[ci] = true
+	for _, l := range p.clauses[ci].Literals {
+		p.occurs[l] = removeIndex(p.occurs[l], ci)
+	}
+}
+
+// strengthen removes literal l from clause ci.
+func (p *preprocessor) strengthen(ci int, l Literal) {
+	old := p.clauses[ci].Literals
+	var literals []Literal
+	for _, other := range old {
+		if other != l {
+			literals = append(literals, other)
+		}
+	}
+	if p.proof != nil {
+		p.proof.add(literals)
+		p.proof.delete(old)
+	}
+	p.clauses[ci] = Clause{Literals: literals}
+	p.occurs[l] = removeIndex(p.occurs[l], ci)
+	p.stats.Strengthened++
+	p.enqueue(ci)
+}
+
+func removeIndex(list []int, ci int) []int {
+	for i, other := range list {
+		if other == ci {
+			list[i] = list[len(list)-1]
+			return list[:len(list)-1]
+		}
+	}
+	return list
+}
+
+// propagateUnits deletes the clauses satisfied by unit clauses, and removes
+// the false literals from the others.
+func (p *preprocessor) propagateUnits() {
+	for len(p.units) > 0 && !p.unsat {
+		ci := p.units[len(p.units)-1]
+		p.units = p.units[:len(p.units)-1]
+		if p.deleted[ci] || len(p.clauses[ci].Literals) != 1 {
+			continue
+		}
+		l := p.clauses[ci].Literals[0]
+		for _, other := range append([]int{}, p.occurs[l]...) {
+			if other != ci {
+				p.delete(other)
+			}
+		}
+		for _, other := range append([]int{}, p.occurs[l.Negate()]...) {
+			p.strengthen(other, l.Negate())
+		}
+	}
+}
+
+// subsumeAll checks the queued clauses for subsumption, until none are left.
+func (p *preprocessor) subsumeAll() {
+	for len(p.queue) > 0 && !p.unsat {
+		ci := p.queue[len(p.queue)-1]
+		p.queue = p.queue[:len(p.queue)-1]
+		p.queued[ci] = false
+		if !p.deleted[ci] {
+			p.subsume(ci)
+		}
+		p.propagateUnits()
+	}
+}
+
+// subsume deletes the clauses which clause ci subsumes, and strengthens those
+// which it subsumes but for one negated literal (self-subsuming resolution).
+// Such clauses have one of the literals of ci's var with the fewest clauses.
+func (p *preprocessor) subsume(ci int) {
+	c := p.clauses[ci].Literals
+	best := c[0]
+	for _, l := range c[1:] {
+		if len(p.occurs[l])+len(p.occurs[l.Negate()]) < len(p.occurs[best])+len(p.occurs[best.Negate()]) {
+			best = l
+		}
+	}
+	for _, l := range []Literal{best, best.Negate()} {
+		for _, di := range append([]int{}, p.occurs[l]...) {
+			if di == ci || p.deleted[di] || len(p.clauses[di].Literals) < len(c) {
+				continue
+			}
+			subsumes, flipped := p.subset(c, p.clauses[di].Literals)
+			switch {
+			case !subsumes:
+			case flipped == Literal(none):
+				p.delete(di)
+				p.stats.Subsumed++
+			default:
+				p.strengthen(di, flipped.Negate())
+			}
+		}
+	}
+}
+
+// subset returns true if each literal of c is in d, but for at most one which
+// is negated in d, which it also returns (or none).
+func (p *preprocessor) subset(c, d []Literal) (bool, Literal) {
+	p.stamp++
+	for _, l := range d {
+		p.marks[l] = p.stamp
+	}
+	flipped := Literal(none)
+	for _, l := range c {
+		switch {
+		case p.marks[l] == p.stamp:
+		case p.marks[l.Negate()] == p.stamp && flipped == Literal(none):
+			flipped = l
+		default:
+			return false, Literal(none)
+		}
+	}
+	return true, flipped
+}
+
+// eliminateAll tries to eliminate each var, those with the fewest clauses
+// first. Returns true if any was eliminated.
+func (p *preprocessor) eliminateAll() bool {
+	numVars := len(p.occurs) / 2
+	vars := make([]VarNum, 0, numVars)
+	for v := 0; v < numVars; v++ {
+		if !p.frozen[v] && !p.eliminated[v] {
+			vars = append(vars, VarNum(v))
+		}
+	}
+	cost := func(v VarNum) int {
+		return len(p.occurs[Positive(v)]) * len(p.occurs[Negative(v)])
+	}
+	sort.SliceStable(vars, func(i, j int) bool { return cost(vars[i]) < cost(vars[j]) })
+	eliminated := false
+	for _, v := range vars {
+		if p.unsat {
+			break
+		}
+		if p.eliminate(v) {
+			eliminated = true
+			p.subsumeAll()
+		}
+	}
+	return eliminated
+}
+
+// eliminate replaces the clauses of v by their resolvents on v, if there are
+// no more resolvents (which are not tautologies) than clauses. Returns true if
+// it eliminated v.
+func (p *preprocessor) eliminate(v VarNum) bool {
+	pos := append([]int{}, p.occurs[Positive(v)]...)
+	neg := append([]int{}, p.occurs[Negative(v)]...)
+	if len(pos)+len(neg) == 0 || len(pos) > maxEliminationOccurrences ||
+		len(neg) > maxEliminationOccurrences {
+		return false
+	}
+	var resolvents [][]Literal
+	for _, pi := range pos {
+		for _, ni := range neg {
+			r, ok := resolve(p.clauses[pi].Literals, p.clauses[ni].Literals, v)
+			if !ok {
+				continue
+			}
+			if len(r) > maxResolventSize || len(resolvents) == len(pos)+len(neg) {
+				return false
+			}
+			resolvents = append(resolvents, r)
+		}
+	}
+	e := elimination{v: v}
+	for _, r := range resolvents {
+		if p.proof != nil {
+			p.proof.add(r)
+		}
+		p.add(r)
+	}
+	for _, ci := range append(pos, neg...) {
+		e.clauses = append(e.clauses, p.clauses[ci])
+		p.delete(ci)
+	}
+	p.push(e)
+	p.eliminated[v] = true
+	p.stats.Eliminated++
+	return true
+}
+
+func (p *preprocessor) push(e elimination) {
+	p.r.stack = append(p.r.stack, e)
+	p.r.stacked[e.v] = true
+}
+
+// resolve returns the resolvent of a clause with v and one with ¬v, or false
+// if it is a tautology.
+func resolve(a, b []Literal, v VarNum) ([]Literal, bool) {
+	var r []Literal
+	for _, l := range a {
+		if l.Var() != v {
+			r = append(r, l)
+		}
+	}
+	for _, l := range b {
+		if l.Var() == v || containsLiteral(r, l) {
+			continue
+		}
+		if containsLiteral(r, l.Negate()) {
+			return nil, false
+		}
+		r = append(r, l)
+	}
+	return r, true
+}
+
+// substituteEquivalences finds the equivalent literals, which imply each other
+// through binary clauses, as the strongly connected components of the
+// implication graph. It replaces each literal by its component's
+// representative throughout the clauses, keeping the equivalences on the
+// stack. Returns true if it substituted any var.
+func (p *preprocessor) substituteEquivalences() bool {
+	component := p.implicationComponents()
+	numVars := len(p.occurs) / 2
+	// The representative of each component is its first literal, preferring
+	// frozen vars, which must stay. The negated component then has the
+	// negated representative.
+	repr := make([]Literal, len(p.occurs))
+	first := make(map[int]Literal)
+	for _, frozen := range []bool{true, false} {
+		for v := 0; v < numVars; v++ {
+			if p.frozen[v] != frozen || p.eliminated[v] {
+				continue
+			}
+			for _, l := range []Literal{Positive(VarNum(v)), Negative(VarNum(v))} {
+				if _, ok := first[component[l]]; !ok {
+					first[component[l]] = l
+				}
+				repr[l] = first[component[l]]
+			}
+		}
+	}
+	var substituted []VarNum
+	for v := 0; v < numVars; v++ {
+		pos, neg := Positive(VarNum(v)), Negative(VarNum(v))
+		if p.eliminated[v] {
+			continue
+		}
+		if component[pos] == component[neg] {
+			// x implies ¬x and the other way around, so ¬x, x and the empty
+			// clause follow by unit propagation.
+			if p.proof != nil {
+				p.proof.add([]Literal{neg})
+			}
+			p.add([]Literal{neg})
+			p.propagateUnits()
+			return true
+		}
+		if repr[pos] != pos && !p.frozen[v] {
+			substituted = append(substituted, VarNum(v))
+		}
+	}
+	if len(substituted) == 0 {
+		return false
+	}
+	var changed []int
+	isChanged := make(map[int]bool)
+	for _, v := range substituted {
+		for _, l := range []Literal{Positive(v), Negative(v)} {
+			for _, ci := range p.occurs[l] {
+				if !isChanged[ci] {
+					isChanged[ci] = true
+					changed = append(changed, ci)
+				}
+			}
+		}
+	}
+	// The substituted clauses follow by unit propagation through the binary
+	// clauses, which are only deleted afterwards.
+	for _, ci := range changed {
+		var literals []Literal
+		for _, l := range p.clauses[ci].Literals {
+			if !p.frozen[l.Var()] {
+				l = repr[l]
+			}
+			literals = append(literals, l)
+		}
+		literals = withoutDuplicates(literals)
+		if !isTautology(literals) {
+			if p.proof != nil {
+				p.proof.add(literals)
+			}
+			p.add(literals)
+		}
+	}
+	for _, ci := range changed {
+		p.delete(ci)
+	}
+	for _, v := range substituted {
+		r := repr[Positive(v)]
+		p.push(elimination{v: v, clauses: []Clause{
+			{Literals: []Literal{Positive(v), r.Negate()}},
+			{Literals: []Literal{Negative(v), r}},
+		}})
+		p.eliminated[v] = true
+		p.stats.Substituted++
+	}
+	p.propagateUnits()
+	return true
+}
+
+// implicationComponents returns the strongly connected component of each
+// literal in the graph where each binary clause a ∨ b is the implications
+// ¬a → b and ¬b → a, by Tarjan's algorithm (with an explicit stack).
+func (p *preprocessor) implicationComponents() []int {
+	n := len(p.occurs)
+	implies := make([][]Literal, n)
+	for ci, c := range p.clauses {
+		if !p.deleted[ci] && len(c.Literals) == 2 {
+			a, b := c.Literals[0], c.Literals[1]
+			implies[a.Negate()] = append(implies[a.Negate()], b)
+			implies[b.Negate()] = append(implies[b.Negate()], a)
+		}
+	}
+	index := make([]int, n) // order of the visit, from 1, or 0 if unvisited
+	low := make([]int, n)
+	component := make([]int, n)
+	onStack := make([]bool, n)
+	var stack []Literal
+	type frame struct {
+		l    Literal
+		next int // index into implies[l]
+	}
+	visits, components := 0, 0
+	visit := func(l Literal) {
+		visits++
+		index[l], low[l] = visits, visits
+		stack = append(stack, l)
+		onStack[l] = true
+	}
+	for root := 0; root < n; root++ {
+		if index[root] != 0 {
+			continue
+		}
+		visit(Literal(root))
+		frames := []frame{{l: Literal(root)}}
+		for len(frames) > 0 {
+			f := &frames[len(frames)-1]
+			l := f.l
+			if f.next < len(implies[l]) {
+				w := implies[l][f.next]
+				f.next++
+				if index[w] == 0 {
+					visit(w)
+					frames = append(frames, frame{l: w})
+				} else if onStack[w] && index[w] < low[l] {
+					low[l] = index[w]
+				}
+				continue
+			}
+			frames = frames[:len(frames)-1]
+			if len(frames) > 0 {
+				if parent := frames[len(frames)-1].l; low[l] < low[parent] {
+					low[parent] = low[l]
+				}
+			}
+			if low[l] == index[l] {
+				for {
+					w := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[w] = false
+					component[w] = components
+					if w == l {
+						break
+					}
+				}
+				components++
+			}
+		}
+	}
+	return component
+}
+
+// eliminateBlocked deletes the clauses which are blocked on a literal l: each
+// resolvent on l with a clause with ¬l is a tautology. Returns true if it
+// deleted any.
+func (p *preprocessor) eliminateBlocked() bool {
+	found := false
+	for v := 0; v < len(p.occurs)/2; v++ {
+		if p.frozen[v] || p.eliminated[v] {
+			continue
+		}
+		for _, l := range []Literal{Positive(VarNum(v)), Negative(VarNum(v))} {
+			if len(p.occurs[l.Negate()]) > maxBlockedOccurrences {
+				continue
+			}
+			for _, ci := range append([]int{}, p.occurs[l]...) {
+				if p.blocked(ci, l) {
+					p.push(elimination{v: VarNum(v), clauses: []Clause{p.clauses[ci]}})
+					p.delete(ci)
+					p.stats.Blocked++
+					found = true
+				}
+			}
+		}
+	}
+	return found
+}
+
+// blocked returns true if each clause with ¬l has the negation of another
+// literal of clause ci.
+func (p *preprocessor) blocked(ci int, l Literal) bool {
+	p.stamp++
+	for _, other := range p.clauses[ci].Literals {
+		p.marks[other] = p.stamp
+	}
+	for _, di := range p.occurs[l.Negate()] {
+		tautology := false
+		for _, other := range p.clauses[di].Literals {
+			if other != l.Negate() && p.marks[other.Negate()] == p.stamp {
+				tautology = true
+				break
+			}
+		}
+		if !tautology {
+			return false
+		}
+	}
+	return true
+}
+
+// frozenVars returns the vars which preprocessing must not eliminate, since
+// they are used by more than the clauses.
+func frozenVars(problem Problem, xors []XorClause) []bool {
+	frozen := make([]bool, problem.Spec.NumVariables)
+	freeze := func(literals []Literal) {
+		for _, l := range literals {
+			frozen[l.Var()] = true
+		}
+	}
+	for _, c := range problem.Soft {
+		freeze(c.Literals)
+	}
+	for _, a := range problem.AtMosts {
+		freeze(a.Literals)
+	}
+	for _, x := range xors {
+		freeze(x.Literals)
+	}
+	for _, c := range problem.Constraints {
+		for _, t := range c.Terms {
+			frozen[t.Literal.Var()] = true
+		}
+	}
+	for _, t := range problem.Objective {
+		frozen[t.Literal.Var()] = true
+	}
+	for _, v := range problem.Independent {
+		frozen[v] = true
+	}
+	return frozen
+}
+
+// restore puts back the removed clauses of the vars of the literals, so they
+// can be used again, e.g., in new clauses or assumptions.
+func (s *Solver) restore(literals []Literal) {
+	for _, l := range literals {
+		if v := l.Var(); int(v) < len(s.elim.stacked) && s.elim.stacked[v] {
+			s.restoreVar(v)
+		}
+	}
+}
+
+func (s *Solver) restoreVar(v VarNum) {
+	r := &s.elim
+	var entries []elimination
+	kept := r.stack[:0]
+	for _, e := range r.stack {
+		if e.v == v {
+			entries = append(entries, e)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	r.stack = kept
+	r.stacked[v] = false
+	// Vars which were removed later may have resolvents of these clauses, or
+	// clauses blocked on their other literals.
+	for _, e := range entries {
+		for _, c := range e.clauses {
+			s.restore(c.Literals)
+		}
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		clauses := entries[i].clauses
+		// With v's literal first, and the clauses with v before those with ¬v,
+		// each clause is a resolution asymmetric tautology for the proof.
+		sort.SliceStable(clauses, func(a, b int) bool {
+			return ownLiteral(clauses[a], v).AsInt() > ownLiteral(clauses[b], v).AsInt()
+		})
+		for _, c := range clauses {
+			literals := []Literal{ownLiteral(c, v)}
+			for _, l := range c.Literals {
+				if l.Var() != v {
+					literals = append(literals, l)
+				}
+			}
+			if s.proof != nil {
+				s.proof.add(literals)
+			}
+			s.AddClause(literals...)
+		}
+	}
+}
+
+func ownLiteral(c Clause, v VarNum) Literal {
+	for _, l := range c.Literals {
+		if l.Var() == v {
+			return l
+		}
+	}
+	return Literal(none)
+}
+
+// Simplified returns the problem as the solver keeps its clauses, e.g., after
+// Options.Preprocess, without learned clauses or other constraints.
+func (s *Solver) Simplified() Problem {
+	free := make(map[ClauseNum]bool)
+	for _, cnum := range s.learned.free {
+		free[cnum] = true
+	}
+	p := Problem{Spec: ProblemSpec{Format: "cnf", NumVariables: s.NumVars()}}
+	for cnum, c := range s.clauses {
+		if !s.learned.isLearned(ClauseNum(cnum)) && !s.learned.explanation[cnum] && !free[ClauseNum(cnum)] {
+			p.Clauses = append(p.Clauses, c)
+		}
+	}
+	p.Spec.NumClauses = len(p.Clauses)
+	return p
+}
@@ -0,0 +1,277 @@
+package s1t
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSubsumeAndStrengthen(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 4 4",
+		"1 2 3 0",
+		"1 2 0",
+		"-1 2 4 0",
+		"-3 -3 3 0",
+	}, t)
+	frozen := []bool{true, true, true, true}
+	var stats Statistics
+	clauses, r := preprocess(problem.Clauses, 4, frozen, nil, &stats)
+	// 1 2 subsumes 1 2 3, and strengthens -1 2 4 to 2 4; the last clause is a
+	// tautology.
+	expected := []Clause{
+		{Literals: []Literal{Positive(0), Positive(1)}},
+		{Literals: []Literal{Positive(1), Positive(3)}},
+	}
+	if !equalClauses(clauses, expected) {
+		t.Errorf("Expected %v, but got %v", expected, clauses)
+	}
+	if stats.Subsumed != 1 || stats.Strengthened != 1 || len(r.stack) != 0 {
+		t.Errorf("Expected 1 subsumed and 1 strengthened clause, but got %+v", stats)
+	}
+}
+
+func TestEliminateVar(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 4 4",
+		"1 2 0",
+		"1 3 0",
+		"-1 4 0",
+		"-2 -3 -4 0",
+	}, t)
+	var stats Statistics
+	p := newPreprocessor(problem.Clauses, 4, make([]bool, 4), nil, &stats)
+	if !p.eliminate(0) {
+		t.Fatalf("Expected to eliminate x1")
+	}
+	// The clause without x1, then the resolvents on x1.
+	expected := []Clause{
+		{Literals: []Literal{Negative(1), Negative(2), Negative(3)}},
+		{Literals: []Literal{Positive(1), Positive(3)}},
+		{Literals: []Literal{Positive(2), Positive(3)}},
+	}
+	if clauses := p.result(); !equalClauses(clauses, expected) {
+		t.Errorf("Expected %v, but got %v", expected, clauses)
+	}
+	// x1 -x2 -x3 x4 needs x1 for 1 2 and 1 3.
+	assignment := []int{none, 0, 0, 1}
+	p.r.extend(assignment)
+	if diff := cmp.Diff([]int{1, 0, 0, 1}, assignment); diff != "" {
+		t.Errorf("extend() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSubstituteEquivalences(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 5 5",
+		"-1 2 0",
+		"-2 3 0",
+		"-3 1 0",
+		"2 4 5 0",
+		"-3 -4 5 0",
+	}, t)
+	var stats Statistics
+	p := newPreprocessor(problem.Clauses, 5, []bool{false, false, false, true, true}, nil, &stats)
+	if !p.substituteEquivalences() {
+		t.Fatalf("Expected to substitute equivalent literals")
+	}
+	// x1, x2 and x3 are equivalent, so x1 replaces the others.
+	expected := []Clause{
+		{Literals: []Literal{Positive(0), Positive(3), Positive(4)}},
+		{Literals: []Literal{Negative(0), Negative(3), Positive(4)}},
+	}
+	if clauses := p.result(); !equalClauses(clauses, expected) {
+		t.Errorf("Expected %v, but got %v", expected, clauses)
+	}
+	if stats.Substituted != 2 {
+		t.Errorf("Expected 2 substituted vars, but got %+v", stats)
+	}
+	assignment := []int{1, none, none, 0, 1}
+	p.r.extend(assignment)
+	if diff := cmp.Diff([]int{1, 1, 1, 0, 1}, assignment); diff != "" {
+		t.Errorf("extend() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEquivalentToNegationIsUnsat(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 3 4",
+		"-1 2 0",
+		"-2 -1 0",
+		"1 3 0",
+		"-3 1 0",
+	}, t)
+	var stats Statistics
+	p := newPreprocessor(problem.Clauses, 3, make([]bool, 3), nil, &stats)
+	p.substituteEquivalences()
+	if !p.unsat {
+		t.Errorf("Expected unsat, but got %v", p.result())
+	}
+}
+
+func TestEliminateBlocked(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 3 3",
+		"1 2 0",
+		"-1 -2 3 0",
+		"2 3 0",
+	}, t)
+	var stats Statistics
+	p := newPreprocessor(problem.Clauses, 3, []bool{false, true, true}, nil, &stats)
+	if !p.eliminateBlocked() {
+		t.Fatalf("Expected blocked clauses")
+	}
+	// 1 2 is blocked on x1, and then -1 -2 3 too.
+	expected := []Clause{{Literals: []Literal{Positive(1), Positive(2)}}}
+	if clauses := p.result(); !equalClauses(clauses, expected) {
+		t.Errorf("Expected %v, but got %v", expected, clauses)
+	}
+	for _, assignment := range [][]int{{none, 0, 1}, {none, 1, 0}, {none, 1, 1}} {
+		p.r.extend(assignment)
+		solution := Solution{Status: Sat, IsSat: true, Assignment: assignment}
+		if ok, c := solution.Satisfies(problem); !ok {
+			t.Errorf("Extended solution %v doesn't satisfy clause %v", assignment, c)
+		}
+	}
+}
+
+func TestEliminateChain(t *testing.T) {
+	// x1 -> x2 -> ... -> x10, with x1 true.
+	lines := []string{"p cnf 10 10", "1 0"}
+	for v := 1; v < 10; v++ {
+		lines = append(lines, fmt.Sprintf("-%d %d 0", v, v+1))
+	}
+	problem := inputToProblem(lines, t)
+	s := NewSolver(problem, Options{Preprocess: true})
+	if simplified := s.Simplified(); len(simplified.Clauses) != 0 {
+		t.Errorf("Expected no clauses left, but got %v", simplified.Clauses)
+	}
+	// The unit clauses are pure, so blocked.
+	if stats := s.Stats(); stats.Blocked != 10 {
+		t.Errorf("Expected 10 blocked clauses, but got %+v", stats)
+	}
+	solution, err := s.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, c := solution.Satisfies(problem); !ok {
+		t.Errorf("Solution %v doesn't satisfy clause %v", solution, c)
+	}
+	// Assuming a var restores its clauses.
+	solution, err = s.SolveAssuming([]Literal{Negative(9)})
+	if err != nil || solution.Status != Unsat {
+		t.Errorf("Expected unsat assuming -10, but got %v, %v", solution, err)
+	}
+}
+
+func TestPreprocessMatchesSolve(t *testing.T) {
+	rng := rand.New(rand.NewSource(24))
+	for i := 0; i < 200; i++ {
+		problem := randomProblem(rng, 30, 90+rng.Intn(60))
+		expected := Solve(problem)
+		solution, err := NewSolver(problem, Options{Preprocess: true}).Solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if solution.Status != expected.Status {
+			t.Fatalf("Expected %v, but got %v for %v", expected.Status, solution.Status, problem)
+		}
+		if solution.Status != Sat {
+			continue
+		}
+		if ok, c := solution.Satisfies(problem); !ok {
+			t.Fatalf("Solution %v doesn't satisfy clause %v of %v", solution, c, problem)
+		}
+	}
+}
+
+func TestPreprocessRestoresEliminatedVars(t *testing.T) {
+	rng := rand.New(rand.NewSource(25))
+	for i := 0; i < 20; i++ {
+		problem := randomProblem(rng, 10, 20+rng.Intn(20))
+		count := func(opts Options) int {
+			s := NewSolver(problem, opts)
+			models := 0
+			for {
+				solution, err := s.Solve()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if solution.Status == Unsat {
+					return models
+				}
+				if ok, c := solution.Satisfies(problem); !ok {
+					t.Fatalf("Solution %v doesn't satisfy clause %v of %v", solution, c, problem)
+				}
+				models++
+				blockModel(t, s, solution)
+			}
+		}
+		if expected, got := count(Options{}), count(Options{Preprocess: true}); got != expected {
+			t.Errorf("Expected %d models, but got %d for %v", expected, got, problem)
+		}
+	}
+}
+
+func TestPreprocessKeepsConstraintVars(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 4 3",
+		"1 2 0",
+		"-2 3 0",
+		"x3 4 0",
+	}, t)
+	s := NewSolver(problem, Options{Preprocess: true})
+	if s.elim.stacked[2] || s.elim.stacked[3] {
+		t.Errorf("Expected the xor's vars to stay, but got %v", s.elim.stacked)
+	}
+	solution, err := s.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, _ := solution.Satisfies(problem)
+	xorsOK, _ := solution.SatisfiesXors(problem)
+	if !ok || !xorsOK {
+		t.Errorf("Expected a solution, but got %v", solution)
+	}
+}
+
+func TestPreprocessProof(t *testing.T) {
+	problem := inputToProblem(pigeonHoleLines(5), t)
+	var proof bytes.Buffer
+	s := NewSolver(problem, Options{Proof: &proof, Preprocess: true})
+	if s.Stats().Eliminated == 0 {
+		t.Errorf("Expected eliminated vars")
+	}
+	solution, err := s.Solve()
+	if err != nil || solution.Status != Unsat {
+		t.Fatalf("Expected unsat, but got %v, %v", solution, err)
+	}
+	checkTextProof(t, problem, proof.String())
+}
+
+func TestReconstructionExtend(t *testing.T) {
+	// x1 was eliminated with x1 | x2 and -x1 | x3.
+	r := reconstruction{
+		stack: []elimination{{v: 0, clauses: []Clause{
+			{Literals: []Literal{Positive(0), Positive(1)}},
+			{Literals: []Literal{Negative(0), Positive(2)}},
+		}}},
+		stacked: []bool{true, false, false},
+	}
+	for _, c := range []struct {
+		assignment []int
+		expected   []int
+	}{
+		{[]int{none, 0, 1}, []int{1, 0, 1}},
+		{[]int{none, 1, 0}, []int{0, 1, 0}},
+		{[]int{none, 1, 1}, []int{0, 1, 1}},
+	} {
+		r.extend(c.assignment)
+		if diff := cmp.Diff(c.expected, c.assignment); diff != "" {
+			t.Errorf("extend() mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
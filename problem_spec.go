@@ -6,15 +6,41 @@ import (
 
 // Problem is a specification of a problem instance.
 type Problem struct {
-	Spec    ProblemSpec
+	Spec ProblemSpec
+	// Clauses are the clauses to satisfy (the hard clauses of a "wcnf" problem).
 	Clauses []Clause
+	// Soft lists the soft clauses of a "wcnf" problem.
+	Soft []SoftClause
+	// Symbols names the input vars, if the input had names (see ParseFormula).
+	Symbols *SymbolTable
+	// Independent lists the vars of "c ind" lines, if any, which Count
+	// projects onto.
+	Independent []VarNum
+	// AtMosts are cardinality constraints to satisfy along with the clauses,
+	// from the "k" lines of a "cnf" problem.
+	AtMosts []AtMost
+	// Xors are xor clauses to satisfy along with the clauses, from the "x"
+	// lines of a "cnf" problem.
+	Xors []XorClause
+	// Constraints are pseudo-Boolean constraints to satisfy along with the
+	// clauses, e.g., from an "opb" problem.
+	Constraints []PBConstraint
+	// Objective is a sum of terms to minimize, from the "min:" line of an
+	// "opb" problem, or nil if there is none.
+	Objective []PBTerm
 }
 
 // ProblemSpec represents the shape of the input problem.
 type ProblemSpec struct {
-	Format       string // TODO(jvoung): handle "sat" format, not just "cnf"
+	Format       string // "cnf", "wcnf", "opb", or "sat" with optional "x" and "e" extensions
 	NumVariables int
 	NumClauses   int
+	// NumAuxVariables is the number of vars at the end which were not in the
+	// input, but were added to transform a "sat" formula to clauses.
+	NumAuxVariables int
+	// TopWeight is the weight of hard clauses given by a "p wcnf" spec line, or
+	// 0 if there is none.
+	TopWeight uint64
 }
 
 // ClauseNum is the index of a clause in the Problem's clause list (0 - NumClauses)
@@ -51,6 +77,23 @@ func (l Literal) Negate() Literal {
 	return Literal(l ^ 1)
 }
 
+// Dimacs returns the DIMACS number of the literal (vars are numbered from 1,
+// and negative if negated).
+func (l Literal) Dimacs() int {
+	if l.AsInt() == 0 {
+		return -int(l.Var()) - 1
+	}
+	return int(l.Var()) + 1
+}
+
+// FromDimacs returns the Literal for a non-zero DIMACS number.
+func FromDimacs(num int) Literal {
+	if num < 0 {
+		return Negative(VarNum(-num - 1))
+	}
+	return Positive(VarNum(num - 1))
+}
+
 func (l Literal) String() string {
 	if l.AsInt() == 0 {
 		return "¬" + l.Var().String()
@@ -71,3 +114,76 @@ type Clause struct {
 func (c *Clause) Empty() bool {
 	return len(c.Literals) == 0
 }
+
+// AtMost is a cardinality constraint: at most K of the Literals are true.
+type AtMost struct {
+	Literals []Literal
+	K        int
+}
+
+// XorClause is a collection of Literals in an exclusive or: an odd number of
+// them are true.
+type XorClause struct {
+	Literals []Literal
+}
+
+// SoftClause is a clause which may be falsified, at the cost of its weight.
+type SoftClause struct {
+	Clause
+	Weight uint64
+}
+
+// PBTerm is a literal with an integer coefficient, which counts the
+// coefficient if the literal is true.
+type PBTerm struct {
+	Coefficient int64
+	Literal     Literal
+}
+
+// PBConstraint is a pseudo-Boolean constraint: the sum of the terms is at
+// least the bound, e.g., 3x1 + 2x2 + x3 >= 4. Coefficients may be negative.
+type PBConstraint struct {
+	Terms []PBTerm
+	Bound int64
+}
+
+// SymbolTable maps the names of vars to VarNums, and back.
+type SymbolTable struct {
+	names []string
+	vars  map[string]VarNum
+}
+
+// NewSymbolTable returns an empty symbol table.
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{vars: make(map[string]VarNum)}
+}
+
+// Intern returns the var of a name, which is the next var if the name is new.
+func (t *SymbolTable) Intern(name string) VarNum {
+	if v, ok := t.vars[name]; ok {
+		return v
+	}
+	v := VarNum(len(t.names))
+	t.names = append(t.names, name)
+	t.vars[name] = v
+	return v
+}
+
+// Var returns the var of a name, or false if there is none.
+func (t *SymbolTable) Var(name string) (VarNum, bool) {
+	v, ok := t.vars[name]
+	return v, ok
+}
+
+// Name returns the name of a var, or its String if it has no name.
+func (t *SymbolTable) Name(v VarNum) string {
+	if int(v) < len(t.names) {
+		return t.names[v]
+	}
+	return v.String()
+}
+
+// Len returns the number of names.
+func (t *SymbolTable) Len() int {
+	return len(t.names)
+}
@@ -0,0 +1,87 @@
+// DRAT proofs of unsatisfiability: the learned clauses as they are added and
+// deleted, ending with the empty clause, for checkers like drat-trim.
+
+package s1t
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// proofWriter writes DRAT proof steps in text or binary format.
+// The first write error is kept and reported by flush.
+type proofWriter struct {
+	w      *bufio.Writer
+	binary bool
+	buf    []byte
+	ended  bool // the empty clause was written
+	err    error
+}
+
+func newProofWriter(w io.Writer, binary bool) *proofWriter {
+	return &proofWriter{w: bufio.NewWriter(w), binary: binary}
+}
+
+// add writes a clause that follows from the problem and the earlier clauses.
+func (p *proofWriter) add(literals []Literal) {
+	p.write('a', literals)
+}
+
+// delete writes a clause that is no longer used.
+func (p *proofWriter) delete(literals []Literal) {
+	p.write('d', literals)
+}
+
+// addEmpty writes the empty clause, once, which concludes the proof.
+func (p *proofWriter) addEmpty() {
+	if !p.ended {
+		p.ended = true
+		p.add(nil)
+	}
+}
+
+func (p *proofWriter) write(kind byte, literals []Literal) {
+	if p.err != nil {
+		return
+	}
+	p.buf = p.buf[:0]
+	if p.binary {
+		p.buf = append(p.buf, kind)
+		for _, l := range literals {
+			// Binary DRAT maps literal ±v to 2v (+1 if negative), written as a
+			// little-endian variable-length integer with 7 bits per byte.
+			u := 2 * uint(l.Var()+1)
+			if l.AsInt() == 0 {
+				u++
+			}
+			for u > 0x7f {
+				p.buf = append(p.buf, byte(u&0x7f|0x80))
+				u >>= 7
+			}
+			p.buf = append(p.buf, byte(u))
+		}
+		p.buf = append(p.buf, 0)
+	} else {
+		if kind == 'd' {
+			p.buf = append(p.buf, "d "...)
+		}
+		for _, l := range literals {
+			p.buf = strconv.AppendInt(p.buf, int64(l.Dimacs()), 10)
+			p.buf = append(p.buf, ' ')
+		}
+		p.buf = append(p.buf, "0\n"...)
+	}
+	_, p.err = p.w.Write(p.buf)
+}
+
+func (p *proofWriter) flush() error {
+	if p.err == nil {
+		p.err = p.w.Flush()
+	}
+	if p.err != nil {
+		return fmt.Errorf("Failed to write proof: %v", p.err)
+	}
+	return nil
+}
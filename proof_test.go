@@ -0,0 +1,170 @@
+package s1t
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// hasRUP returns true if unit propagation on clauses and the negation of c
+// leads to a conflict, i.e. c is a reverse unit propagation consequence.
+func hasRUP(clauses [][]int, c []int) bool {
+	value := make(map[int]bool)
+	for _, l := range c {
+		value[-l] = true
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, clause := range clauses {
+			var unassigned []int
+			satisfied := false
+			for _, l := range clause {
+				if value[l] {
+					satisfied = true
+					break
+				}
+				if !value[-l] {
+					unassigned = append(unassigned, l)
+				}
+			}
+			if satisfied {
+				continue
+			}
+			switch len(unassigned) {
+			case 0:
+				return true
+			case 1:
+				value[unassigned[0]] = true
+				changed = true
+			}
+		}
+	}
+	return false
+}
+
+// checkTextProof checks that each added clause of the proof is RUP and that
+// the proof ends with the empty clause.
+func checkTextProof(t *testing.T, problem Problem, proof string) {
+	var clauses [][]int
+	for _, c := range problem.Clauses {
+		var ints []int
+		for _, l := range c.Literals {
+			ints = append(ints, l.Dimacs())
+		}
+		clauses = append(clauses, ints)
+	}
+	var deleted, added int
+	lines := strings.Split(strings.TrimSpace(proof), "\n")
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		isDelete := fields[0] == "d"
+		if isDelete {
+			fields = fields[1:]
+		}
+		var c []int
+		for _, f := range fields[:len(fields)-1] {
+			l, err := strconv.Atoi(f)
+			if err != nil {
+				t.Fatalf("Bad proof line %q: %v", line, err)
+			}
+			c = append(c, l)
+		}
+		if isDelete {
+			for i, other := range clauses {
+				if cmp.Equal(other, c) {
+					clauses = append(clauses[:i], clauses[i+1:]...)
+					deleted++
+					break
+				}
+			}
+			continue
+		}
+		if !hasRUP(clauses, c) {
+			t.Fatalf("Proof clause %v is not RUP", c)
+		}
+		clauses = append(clauses, c)
+		added++
+	}
+	if lines[len(lines)-1] != "0" {
+		t.Errorf("Expected the proof to end with the empty clause, got %q", lines[len(lines)-1])
+	}
+	if added == 0 || deleted == 0 {
+		t.Errorf("Expected added and deleted clauses, but got %d and %d", added, deleted)
+	}
+}
+
+// pigeonHoleLines returns the DIMACS lines for putting n+1 pigeons in n holes.
+func pigeonHoleLines(n int) []string {
+	pigeonIn := func(p, h int) int { return p*n + h + 1 }
+	var lines []string
+	for p := 0; p <= n; p++ {
+		var line []string
+		for h := 0; h < n; h++ {
+			line = append(line, strconv.Itoa(pigeonIn(p, h)))
+		}
+		lines = append(lines, strings.Join(line, " ")+" 0")
+	}
+	for h := 0; h < n; h++ {
+		for p := 0; p <= n; p++ {
+			for q := p + 1; q <= n; q++ {
+				lines = append(lines, fmt.Sprintf("-%d -%d 0", pigeonIn(p, h), pigeonIn(q, h)))
+			}
+		}
+	}
+	header := fmt.Sprintf("p cnf %d %d", n*(n+1), len(lines))
+	return append([]string{header}, lines...)
+}
+
+func TestTextProof(t *testing.T) {
+	problem := inputToProblem(pigeonHoleLines(5), t)
+	var proof bytes.Buffer
+	s := NewSolver(problem, Options{Proof: &proof, ReduceInterval: 20})
+	solution, err := s.Solve()
+	if err != nil || solution.Status != Unsat {
+		t.Fatalf("Expected unsat, but got %v, %v", solution, err)
+	}
+	checkTextProof(t, problem, proof.String())
+}
+
+func TestBinaryProofEncoding(t *testing.T) {
+	var proof bytes.Buffer
+	p := newProofWriter(&proof, true)
+	p.add([]Literal{Positive(0), Negative(1)})
+	p.delete([]Literal{Negative(63), Positive(64)})
+	p.addEmpty()
+	p.addEmpty()
+	if err := p.flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		'a', 2, 5, 0,
+		'd', 129, 1, 130, 1, 0,
+		'a', 0,
+	}
+	if !cmp.Equal(proof.Bytes(), expected) {
+		t.Errorf("Expected binary proof %v, but got %v", expected, proof.Bytes())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestProofWriteError(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 1 2",
+		"1 0",
+		"-1 0",
+	}, t)
+	solution, err := NewSolver(problem, Options{Proof: failingWriter{}}).Solve()
+	if solution.Status != Unsat || err == nil {
+		t.Errorf("Expected unsat with a proof error, but got %v, %v", solution, err)
+	}
+}
@@ -0,0 +1,215 @@
+// DRAT proofs: clauses to add (which must have RUP or RAT) and to delete.
+
+package proofcheck
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/jvoung/s1t"
+)
+
+// dratStep adds or deletes the clause with an index into the database.
+// Deleting a clause which is not in the database is ignored (clause is none).
+type dratStep struct {
+	delete bool
+	clause int
+}
+
+// CheckDRAT verifies a DRAT proof, in text or binary format, that problem is
+// unsat. Returns nil if the proof derives the empty clause and each clause it
+// adds on the way to it has reverse unit propagation (or is a resolution
+// asymmetric tautology on its first literal). Only the clauses that the
+// empty clause depends on are checked, going backwards from it.
+func CheckDRAT(problem s1t.Problem, proof io.Reader) error {
+	if hasEmptyClause(problem) {
+		return nil
+	}
+	data, err := ioutil.ReadAll(proof)
+	if err != nil {
+		return err
+	}
+	var lines [][]s1t.Literal
+	var deletes []bool
+	if isBinaryDRAT(data) {
+		lines, deletes, err = parseBinaryDRAT(data)
+	} else {
+		lines, deletes, err = parseTextDRAT(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Forward: apply the steps until the empty clause.
+	db := newDatabase(problem)
+	byKey := make(map[string][]int)
+	for i, c := range db.clauses {
+		key := clauseKey(c)
+		byKey[key] = append(byKey[key], i)
+	}
+	var steps []dratStep
+	empty := none
+	for i, literals := range lines {
+		key := clauseKey(literals)
+		if !deletes[i] {
+			c := db.add(literals)
+			byKey[key] = append(byKey[key], c)
+			steps = append(steps, dratStep{false, c})
+			if len(literals) == 0 {
+				empty = c
+				break
+			}
+			continue
+		}
+		step := dratStep{true, none}
+		if matches := byKey[key]; len(matches) > 0 {
+			step.clause = matches[len(matches)-1]
+			byKey[key] = matches[:len(matches)-1]
+			db.active[step.clause] = false
+		}
+		steps = append(steps, step)
+	}
+	if empty == none {
+		return fmt.Errorf("Proof does not derive the empty clause")
+	}
+
+	// Backward: undo the steps, and check the clauses marked as core.
+	db.core[empty] = true
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		if step.clause == none {
+			continue
+		}
+		if step.delete {
+			db.active[step.clause] = true
+			continue
+		}
+		db.active[step.clause] = false
+		if !db.core[step.clause] {
+			continue
+		}
+		c := db.clauses[step.clause]
+		if !db.hasRUP(c) && !db.hasRAT(c, db.pivots[step.clause]) {
+			return fmt.Errorf("Proof step %d adds clause %v, which is neither RUP nor RAT",
+				i+1, dimacsString(c))
+		}
+	}
+	return nil
+}
+
+// binaryDetectionPrefix is the number of bytes that isBinaryDRAT looks at.
+const binaryDetectionPrefix = 1024
+
+// isBinaryDRAT guesses the format like drat-trim: text proofs only have
+// printable characters and whitespace, while binary proofs end each clause
+// with a 0 byte, and small literals are control characters.
+func isBinaryDRAT(data []byte) bool {
+	prefix := data
+	if len(prefix) > binaryDetectionPrefix {
+		prefix = prefix[:binaryDetectionPrefix]
+	}
+	for _, b := range prefix {
+		if (b < ' ' || b > '~') && b != '\n' && b != '\r' && b != '\t' {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTextDRAT(data []byte) ([][]s1t.Literal, []bool, error) {
+	var lines [][]s1t.Literal
+	var deletes []bool
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<30)
+	var clause []s1t.Literal
+	isDelete := false
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && strings.HasPrefix(fields[0], "c") {
+			continue
+		}
+		for _, f := range fields {
+			if f == "d" && len(clause) == 0 {
+				isDelete = true
+				continue
+			}
+			num, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Failed to parse %q on proof line %d", f, lineNum)
+			}
+			if num == 0 {
+				lines = append(lines, clause)
+				deletes = append(deletes, isDelete)
+				clause = nil
+				isDelete = false
+				continue
+			}
+			clause = append(clause, s1t.FromDimacs(num))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(clause) > 0 || isDelete {
+		return nil, nil, fmt.Errorf("Proof ends with an unterminated clause")
+	}
+	return lines, deletes, nil
+}
+
+func parseBinaryDRAT(data []byte) ([][]s1t.Literal, []bool, error) {
+	var lines [][]s1t.Literal
+	var deletes []bool
+	for i := 0; i < len(data); {
+		kind := data[i]
+		if kind != 'a' && kind != 'd' {
+			return nil, nil, fmt.Errorf("Unexpected byte %#x at offset %d of binary proof", kind, i)
+		}
+		i++
+		var clause []s1t.Literal
+		for {
+			var u uint
+			var shift uint
+			for {
+				if i == len(data) {
+					return nil, nil, fmt.Errorf("Binary proof ends with an unterminated clause")
+				}
+				b := data[i]
+				i++
+				u |= uint(b&0x7f) << shift
+				shift += 7
+				if b&0x80 == 0 {
+					break
+				}
+			}
+			if u == 0 {
+				break
+			}
+			if u == 1 {
+				return nil, nil, fmt.Errorf("Invalid literal at offset %d of binary proof", i-1)
+			}
+			v := s1t.VarNum(u/2 - 1)
+			if u%2 == 1 {
+				clause = append(clause, s1t.Negative(v))
+			} else {
+				clause = append(clause, s1t.Positive(v))
+			}
+		}
+		lines = append(lines, clause)
+		deletes = append(deletes, kind == 'd')
+	}
+	return lines, deletes, nil
+}
+
+func dimacsString(literals []s1t.Literal) string {
+	var b strings.Builder
+	for _, l := range literals {
+		fmt.Fprintf(&b, "%d ", l.Dimacs())
+	}
+	b.WriteString("0")
+	return b.String()
+}
@@ -0,0 +1,160 @@
+// LRAT proofs: like DRAT, but each added clause lists the clauses (hints)
+// which become unit, in order, and then falsified under its negation.
+
+package proofcheck
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jvoung/s1t"
+)
+
+// CheckLRAT verifies a text LRAT proof that problem is unsat. The problem
+// clauses have IDs from 1 in order. Returns nil if the proof derives the empty
+// clause, and each added clause is a RUP consequence by its hints. RAT steps
+// (negative hints) are not supported.
+func CheckLRAT(problem s1t.Problem, proof io.Reader) error {
+	if hasEmptyClause(problem) {
+		return nil
+	}
+	db := newDatabase(s1t.Problem{Spec: problem.Spec})
+	clauses := make(map[int][]s1t.Literal)
+	for i, c := range problem.Clauses {
+		clauses[i+1] = c.Literals
+	}
+	scanner := bufio.NewScanner(proof)
+	scanner.Buffer(nil, 1<<30)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "c") {
+			continue
+		}
+		if len(fields) > 1 && fields[1] == "d" {
+			ids, err := parseInts(fields[2:], lineNum)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				delete(clauses, id)
+			}
+			continue
+		}
+		nums, err := parseInts(fields, lineNum)
+		if err != nil {
+			return err
+		}
+		// id literals... 0 hints... 0
+		split := indexOfZero(nums[1:]) + 1
+		if split == 0 || split == len(nums)-1 || nums[len(nums)-1] != 0 {
+			return fmt.Errorf("Malformed proof line %d", lineNum)
+		}
+		id := nums[0]
+		var clause []s1t.Literal
+		for _, num := range nums[1:split] {
+			clause = append(clause, s1t.FromDimacs(num))
+		}
+		hints := nums[split+1 : len(nums)-1]
+		db.growVars(maxVar(clause))
+		if err := checkHints(db, clauses, clause, hints); err != nil {
+			return fmt.Errorf("Proof line %d: %v", lineNum, err)
+		}
+		if len(clause) == 0 {
+			return nil
+		}
+		clauses[id] = clause
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("Proof does not derive the empty clause")
+}
+
+// checkHints assumes the negation of clause and checks that the hints become
+// unit, and finally falsified.
+func checkHints(db *database, clauses map[int][]s1t.Literal, clause []s1t.Literal,
+	hints []int) error {
+	defer db.reset()
+	for _, l := range clause {
+		switch db.value(l) {
+		case 1:
+			// A tautology.
+			return nil
+		case none:
+			db.assign(l.Negate(), none)
+		}
+	}
+	for _, id := range hints {
+		if id < 0 {
+			return fmt.Errorf("RAT hints are not supported")
+		}
+		hint, ok := clauses[id]
+		if !ok {
+			return fmt.Errorf("Hint %d is not a clause", id)
+		}
+		db.growVars(maxVar(hint))
+		var unit []s1t.Literal
+		for _, l := range hint {
+			switch db.value(l) {
+			case 1:
+				return fmt.Errorf("Hint %d is satisfied", id)
+			case none:
+				if !contains(unit, l) {
+					unit = append(unit, l)
+				}
+			}
+		}
+		switch len(unit) {
+		case 0:
+			return nil
+		case 1:
+			db.assign(unit[0], none)
+		default:
+			return fmt.Errorf("Hint %d is not unit", id)
+		}
+	}
+	return fmt.Errorf("Hints do not lead to a conflict")
+}
+
+func parseInts(fields []string, lineNum int) ([]int, error) {
+	nums := make([]int, len(fields))
+	for i, f := range fields {
+		num, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse %q on proof line %d", f, lineNum)
+		}
+		nums[i] = num
+	}
+	return nums, nil
+}
+
+func indexOfZero(nums []int) int {
+	for i, num := range nums {
+		if num == 0 {
+			return i
+		}
+	}
+	return none
+}
+
+func maxVar(literals []s1t.Literal) int {
+	max := 0
+	for _, l := range literals {
+		if int(l.Var()) >= max {
+			max = int(l.Var()) + 1
+		}
+	}
+	return max
+}
+
+func hasEmptyClause(problem s1t.Problem) bool {
+	for _, c := range problem.Clauses {
+		if c.Empty() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+// Package proofcheck verifies proofs that a problem is unsat, in the DRAT
+// format (as written by s1t.Options.Proof) or the LRAT format.
+package proofcheck
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jvoung/s1t"
+)
+
+const none = -1
+
+// database holds the problem clauses followed by the clauses added by a
+// proof, and does unit propagation over the active ones.
+type database struct {
+	// The literals of each clause are reordered for the watches, so the
+	// first literal as added, which is the RAT pivot, is kept apart.
+	clauses [][]s1t.Literal
+	pivots  []s1t.Literal
+	active  []bool
+	core    []bool // clauses used to derive the empty clause
+	watches [][]int
+	units   []int // indices of unit clauses, maybe inactive
+
+	// Assignment during a check, undone afterwards.
+	values []int // as in Literal.AsInt, or none
+	reason []int // clause that implied the var, or none
+	trail  []s1t.Literal
+	seen   []bool
+}
+
+func newDatabase(problem s1t.Problem) *database {
+	db := &database{}
+	db.growVars(problem.Spec.NumVariables)
+	for _, c := range problem.Clauses {
+		db.add(c.Literals)
+	}
+	return db
+}
+
+func (db *database) growVars(numVars int) {
+	for len(db.values) < numVars {
+		db.values = append(db.values, none)
+		db.reason = append(db.reason, none)
+		db.seen = append(db.seen, false)
+		db.watches = append(db.watches, nil, nil)
+	}
+}
+
+// add stores an active copy of the clause, without duplicate literals, and
+// returns its index.
+func (db *database) add(literals []s1t.Literal) int {
+	c := make([]s1t.Literal, 0, len(literals))
+	for _, l := range literals {
+		if !contains(c, l) {
+			c = append(c, l)
+		}
+	}
+	db.growVars(maxVar(c))
+	i := len(db.clauses)
+	db.clauses = append(db.clauses, c)
+	pivot := s1t.Literal(none)
+	if len(c) > 0 {
+		pivot = c[0]
+	}
+	db.pivots = append(db.pivots, pivot)
+	db.active = append(db.active, true)
+	db.core = append(db.core, false)
+	switch len(c) {
+	case 0:
+	case 1:
+		db.units = append(db.units, i)
+	default:
+		db.watches[c[0]] = append(db.watches[c[0]], i)
+		db.watches[c[1]] = append(db.watches[c[1]], i)
+	}
+	return i
+}
+
+func (db *database) value(l s1t.Literal) int {
+	v := db.values[l.Var()]
+	if v == none {
+		return none
+	}
+	if v == l.AsInt() {
+		return 1
+	}
+	return 0
+}
+
+func (db *database) assign(l s1t.Literal, reason int) {
+	db.values[l.Var()] = l.AsInt()
+	db.reason[l.Var()] = reason
+	db.trail = append(db.trail, l)
+}
+
+func (db *database) reset() {
+	for _, l := range db.trail {
+		db.values[l.Var()] = none
+		db.reason[l.Var()] = none
+	}
+	db.trail = db.trail[:0]
+}
+
+// hasRUP returns true if the clause is a reverse unit propagation consequence
+// of the active clauses: assuming its negation, unit propagation leads to a
+// conflict. Marks the clauses involved in the conflict as core.
+func (db *database) hasRUP(clause []s1t.Literal) bool {
+	defer db.reset()
+	for _, l := range clause {
+		switch db.value(l) {
+		case 1:
+			// A tautology.
+			return true
+		case none:
+			db.assign(l.Negate(), none)
+		}
+	}
+	for _, i := range db.units {
+		if !db.active[i] {
+			continue
+		}
+		l := db.clauses[i][0]
+		switch db.value(l) {
+		case 0:
+			db.markCore(i)
+			return true
+		case none:
+			db.assign(l, i)
+		}
+	}
+	if conflict := db.propagate(); conflict != none {
+		db.markCore(conflict)
+		return true
+	}
+	return false
+}
+
+// hasRAT returns true if the clause is a resolution asymmetric tautology on
+// the pivot, one of its literals: each resolvent with an active clause has
+// RUP.
+func (db *database) hasRAT(clause []s1t.Literal, pivot s1t.Literal) bool {
+	if len(clause) == 0 {
+		return false
+	}
+	for i, other := range db.clauses {
+		if !db.active[i] || !contains(other, pivot.Negate()) {
+			continue
+		}
+		resolvent := append([]s1t.Literal{}, clause...)
+		for _, l := range other {
+			if l != pivot.Negate() && !contains(resolvent, l) {
+				resolvent = append(resolvent, l)
+			}
+		}
+		if !db.hasRUP(resolvent) {
+			return false
+		}
+		db.core[i] = true
+	}
+	return true
+}
+
+// propagate does unit propagation from the trail, and returns the index of a
+// falsified clause, or none.
+func (db *database) propagate() int {
+	for head := 0; head < len(db.trail); head++ {
+		falsified := db.trail[head].Negate()
+		watchers := db.watches[falsified]
+		kept := watchers[:0]
+		conflict := none
+		for w, i := range watchers {
+			if !db.active[i] {
+				kept = append(kept, i)
+				continue
+			}
+			c := db.clauses[i]
+			if c[0] == falsified {
+				c[0], c[1] = c[1], c[0]
+			}
+			if db.value(c[0]) == 1 {
+				kept = append(kept, i)
+				continue
+			}
+			moved := false
+			for k := 2; k < len(c); k++ {
+				if db.value(c[k]) != 0 {
+					c[1], c[k] = c[k], c[1]
+					db.watches[c[1]] = append(db.watches[c[1]], i)
+					moved = true
+					break
+				}
+			}
+			if moved {
+				continue
+			}
+			kept = append(kept, i)
+			if db.value(c[0]) == 0 {
+				conflict = i
+				// Keep the rest of the watchers.
+				kept = append(kept, watchers[w+1:]...)
+				break
+			}
+			db.assign(c[0], i)
+		}
+		db.watches[falsified] = kept
+		if conflict != none {
+			return conflict
+		}
+	}
+	return none
+}
+
+// markCore marks the conflict clause and the reasons of its assignments.
+func (db *database) markCore(conflict int) {
+	db.core[conflict] = true
+	for _, l := range db.clauses[conflict] {
+		db.seen[l.Var()] = true
+	}
+	for j := len(db.trail) - 1; j >= 0; j-- {
+		v := db.trail[j].Var()
+		if !db.seen[v] {
+			continue
+		}
+		db.seen[v] = false
+		r := db.reason[v]
+		if r == none {
+			continue
+		}
+		db.core[r] = true
+		for _, l := range db.clauses[r] {
+			if l.Var() != v {
+				db.seen[l.Var()] = true
+			}
+		}
+	}
+}
+
+// clauseKey identifies a clause regardless of the order of its literals.
+func clauseKey(literals []s1t.Literal) string {
+	sorted := append([]s1t.Literal{}, literals...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var key []byte
+	for i, l := range sorted {
+		if i > 0 && sorted[i-1] == l {
+			continue
+		}
+		key = append(key, fmt.Sprintf("%d ", l.Dimacs())...)
+	}
+	return string(key)
+}
+
+func contains(literals []s1t.Literal, l s1t.Literal) bool {
+	for _, other := range literals {
+		if other == l {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package proofcheck
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jvoung/s1t"
+)
+
+func parseOrDie(t *testing.T, lines ...string) s1t.Problem {
+	problem, err := s1t.ParseDimacs(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+	return problem
+}
+
+func TestCheckSolverProofs(t *testing.T) {
+	input, err := os.Open("../test_cnf/hole6.cnf")
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	defer input.Close()
+	problem, err := s1t.ParseDimacs(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, binary := range []bool{false, true} {
+		var proof bytes.Buffer
+		opts := s1t.Options{Proof: &proof, BinaryProof: binary, ReduceInterval: 50}
+		solution, err := s1t.NewSolver(problem, opts).Solve()
+		if err != nil || solution.Status != s1t.Unsat {
+			t.Fatalf("Expected unsat, but got %v, %v", solution, err)
+		}
+		if err := CheckDRAT(problem, &proof); err != nil {
+			t.Errorf("Binary %v, expected a valid proof, but got %v", binary, err)
+		}
+	}
+}
+
+func TestCheckDRAT(t *testing.T) {
+	unsat := parseOrDie(t, "p cnf 2 4", "1 2 0", "-1 2 0", "1 -2 0", "-1 -2 0")
+	sat := parseOrDie(t, "p cnf 2 3", "1 2 0", "-1 2 0", "1 -2 0")
+	unused := parseOrDie(t, "p cnf 4 5", "1 2 0", "-1 2 0", "1 -2 0", "-1 -2 0", "-3 4 0")
+	cases := []struct {
+		problem s1t.Problem
+		proof   string
+		valid   bool
+	}{
+		{unsat, "2 0\n0\n", true},
+		{unsat, "c comment\n2 0\nd 1 2 0\n0\n", true},
+		// Deleting a clause needed by the empty clause.
+		{unsat, "2 0\nd 1 -2 0\n0\n", false},
+		{unsat, "2 0\n", false},
+		{sat, "2 0\n0\n", false},
+		// Clauses that the empty clause doesn't need are not checked.
+		{unused, "3 0\n2 0\n0\n", true},
+		{unused, "3 0\n0\n", false},
+		{unsat, "2 0\n0", true},
+		{unsat, "2 0\nx 0\n", false},
+		{unsat, "\x61\x04\x00\x61\x00", true},
+		{unsat, "\x61\x04", false},
+		// A binary proof that starts with a deletion of a printable literal.
+		{unsat, "\x64\x20\x21\x00\x61\x04\x00\x61\x00", true},
+	}
+	for _, c := range cases {
+		err := CheckDRAT(c.problem, strings.NewReader(c.proof))
+		if c.valid && err != nil {
+			t.Errorf("Proof %q, expected valid, but got %v", c.proof, err)
+		} else if !c.valid && err == nil {
+			t.Errorf("Proof %q, expected an error", c.proof)
+		}
+	}
+}
+
+func TestRAT(t *testing.T) {
+	db := newDatabase(parseOrDie(t, "p cnf 3 1", "-3 1 0"))
+	// Resolving on 3 gives a tautology.
+	c := []s1t.Literal{s1t.Positive(2), s1t.Negative(0)}
+	if db.hasRUP(c) || !db.hasRAT(c, c[0]) {
+		t.Errorf("Expected %v to be RAT but not RUP", c)
+	}
+	c = []s1t.Literal{s1t.Positive(2), s1t.Positive(1)}
+	if db.hasRAT(c, c[0]) {
+		t.Errorf("Expected %v to not be RAT", c)
+	}
+}
+
+func TestRATPivotMovedByPropagation(t *testing.T) {
+	db := newDatabase(parseOrDie(t, "p cnf 3 1", "-2 3 0"))
+	lemma := db.add([]s1t.Literal{s1t.Positive(0), s1t.Positive(1)})
+	// Checking 1 3 propagates by the lemma, which then has 2 first.
+	if !db.hasRUP([]s1t.Literal{s1t.Positive(0), s1t.Positive(2)}) {
+		t.Fatalf("Expected 1 3 to be RUP")
+	}
+	db.active[lemma] = false
+	c := db.clauses[lemma]
+	if c[0] == db.pivots[lemma] {
+		t.Fatalf("Expected propagation to move the pivot of %v", c)
+	}
+	// The lemma is RAT on 1, which is in no other clause, but not on 2.
+	if db.hasRUP(c) || !db.hasRAT(c, db.pivots[lemma]) || db.hasRAT(c, c[0]) {
+		t.Errorf("Expected %v to be RAT on its pivot only", c)
+	}
+}
+
+func TestCheckLRAT(t *testing.T) {
+	unsat := parseOrDie(t, "p cnf 2 4", "1 2 0", "-1 2 0", "1 -2 0", "-1 -2 0")
+	cases := []struct {
+		proof string
+		valid bool
+	}{
+		{"5 2 0 1 2 0\n6 0 5 3 4 0\n", true},
+		{"5 2 0 1 2 0\n5 d 1 2 0\n6 0 5 3 4 0\n", true},
+		{"5 2 0 1 2 0\n5 d 3 0\n6 0 5 3 4 0\n", false},
+		{"5 2 0 1 2 0\n6 0 5 3 0\n", false},
+		{"5 2 0 1 0\n6 0 5 3 4 0\n", false},
+		{"5 2 0 -1 0\n", false},
+		{"5 2 0 1 2 0\n", false},
+		{"5 2 0 1 2\n", false},
+	}
+	for _, c := range cases {
+		err := CheckLRAT(unsat, strings.NewReader(c.proof))
+		if c.valid && err != nil {
+			t.Errorf("Proof %q, expected valid, but got %v", c.proof, err)
+		} else if !c.valid && err == nil {
+			t.Errorf("Proof %q, expected an error", c.proof)
+		}
+	}
+}
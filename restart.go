@@ -0,0 +1,139 @@
+// Restart policies. A restart backjumps to decision level 0 but keeps learned
+// clauses, variable activities and saved phases.
+
+package s1t
+
+// RestartPolicy selects when the solver restarts its search.
+type RestartPolicy int
+
+const (
+	// LubyRestarts restarts after RestartInterval times the next number of the
+	// Luby sequence (1, 1, 2, 1, 1, 2, 4, ...) of conflicts.
+	LubyRestarts RestartPolicy = iota
+	// GlucoseRestarts restarts when the average literal block distance (LBD)
+	// of recently learned clauses is high compared to the overall average.
+	GlucoseRestarts
+	// GeometricRestarts restarts after RestartInterval conflicts, and grows
+	// the interval by a constant factor after each restart.
+	GeometricRestarts
+	// FixedRestarts restarts after every RestartInterval conflicts.
+	FixedRestarts
+	// NoRestarts never restarts.
+	NoRestarts
+)
+
+const (
+	defaultRestartInterval = 100
+	geometricGrowth        = 1.5
+	// Window and margin for GlucoseRestarts, as in Glucose.
+	glucoseWindow = 50
+	glucoseMargin = 0.8
+)
+
+// restarter counts conflicts to decide when to restart.
+type restarter struct {
+	policy    RestartPolicy
+	interval  int
+	limit     float64 // conflicts allowed before the next restart
+	conflicts int     // conflicts since the last restart
+	restarts  int
+
+	// Ring buffer of LBDs of the most recent learned clauses.
+	recentLBD   []int
+	recentNext  int
+	recentSum   int
+	recentCount int
+	totalLBD    float64
+	totalCount  int
+}
+
+func newRestarter(policy RestartPolicy, interval int) restarter {
+	if interval <= 0 {
+		interval = defaultRestartInterval
+	}
+	r := restarter{
+		policy:   policy,
+		interval: interval,
+	}
+	if policy == GlucoseRestarts {
+		r.recentLBD = make([]int, glucoseWindow)
+	}
+	r.setLimit()
+	return r
+}
+
+// conflict records a conflict which resulted in a clause with the given LBD.
+func (r *restarter) conflict(lbd int) {
+	r.conflicts++
+	if r.policy != GlucoseRestarts {
+		return
+	}
+	r.totalLBD += float64(lbd)
+	r.totalCount++
+	if r.recentCount == len(r.recentLBD) {
+		r.recentSum -= r.recentLBD[r.recentNext]
+	} else {
+		r.recentCount++
+	}
+	r.recentLBD[r.recentNext] = lbd
+	r.recentSum += lbd
+	r.recentNext = (r.recentNext + 1) % len(r.recentLBD)
+}
+
+func (r *restarter) shouldRestart() bool {
+	switch r.policy {
+	case NoRestarts:
+		return false
+	case GlucoseRestarts:
+		if r.recentCount < len(r.recentLBD) {
+			return false
+		}
+		recentAvg := float64(r.recentSum) / float64(r.recentCount)
+		totalAvg := r.totalLBD / float64(r.totalCount)
+		return recentAvg*glucoseMargin > totalAvg
+	default:
+		return float64(r.conflicts) >= r.limit
+	}
+}
+
+// restarted resets the counters after a restart.
+func (r *restarter) restarted() {
+	r.restarts++
+	r.conflicts = 0
+	r.recentNext = 0
+	r.recentSum = 0
+	r.recentCount = 0
+	r.setLimit()
+}
+
+func (r *restarter) setLimit() {
+	switch r.policy {
+	case LubyRestarts:
+		r.limit = float64(r.interval * luby(r.restarts))
+	case GeometricRestarts:
+		if r.restarts == 0 {
+			r.limit = float64(r.interval)
+		} else {
+			r.limit *= geometricGrowth
+		}
+	case FixedRestarts:
+		r.limit = float64(r.interval)
+	}
+}
+
+// luby returns the i-th (from 0) number of the Luby sequence:
+// 1, 1, 2, 1, 1, 2, 4, 1, 1, 2, 1, 1, 2, 4, 8, ...
+func luby(i int) int {
+	// Find the finite subsequence that contains index i, and its size.
+	size, seq := 1, 0
+	for size < i+1 {
+		seq++
+		size = 2*size + 1
+	}
+	for size-1 != i {
+		size = (size - 1) / 2
+		seq--
+		i = i % size
+	}
+	return 1 << uint(seq)
+}
@@ -0,0 +1,100 @@
+package s1t
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLubySequence(t *testing.T) {
+	expected := []int{1, 1, 2, 1, 1, 2, 4, 1, 1, 2, 1, 1, 2, 4, 8, 1}
+	var got []int
+	for i := range expected {
+		got = append(got, luby(i))
+	}
+	if !cmp.Equal(got, expected) {
+		t.Errorf("Expected Luby sequence %v, but got %v", expected, got)
+	}
+}
+
+// Returns the number of conflicts before each of the first n restarts.
+func restartIntervals(r restarter, n int) []int {
+	var intervals []int
+	for len(intervals) < n {
+		r.conflict(1)
+		if r.shouldRestart() {
+			intervals = append(intervals, r.conflicts)
+			r.restarted()
+		}
+	}
+	return intervals
+}
+
+func TestRestartIntervals(t *testing.T) {
+	cases := []struct {
+		policy   RestartPolicy
+		expected []int
+	}{
+		{FixedRestarts, []int{10, 10, 10, 10}},
+		{GeometricRestarts, []int{10, 15, 23, 34}},
+		{LubyRestarts, []int{10, 10, 20, 10, 10, 20, 40}},
+	}
+	for _, c := range cases {
+		got := restartIntervals(newRestarter(c.policy, 10), len(c.expected))
+		if !cmp.Equal(got, c.expected) {
+			t.Errorf("Policy %d, expected intervals %v, but got %v", c.policy, c.expected, got)
+		}
+	}
+}
+
+func TestGlucoseRestart(t *testing.T) {
+	r := newRestarter(GlucoseRestarts, 0)
+	for i := 0; i < 1000; i++ {
+		r.conflict(5)
+		if r.shouldRestart() {
+			t.Fatalf("Unexpected restart with constant LBD after %d conflicts", i)
+		}
+	}
+	for i := 0; i < glucoseWindow; i++ {
+		r.conflict(20)
+	}
+	if !r.shouldRestart() {
+		t.Error("Expected restart after a window of high LBD clauses")
+	}
+	r.restarted()
+	if r.shouldRestart() {
+		t.Error("Expected no restart until the window fills again")
+	}
+}
+
+func TestRestartPolicies(t *testing.T) {
+	for _, policy := range []RestartPolicy{
+		LubyRestarts, GlucoseRestarts, GeometricRestarts, FixedRestarts, NoRestarts} {
+		opts := Options{Restart: policy, RestartInterval: 5}
+		testFromFileWithOptions(t, "test_cnf/hole6.cnf", opts, unsat())
+		testFromFileSelfCheckWithOptions(t, "test_cnf/blocksworld_medium.cnf", opts)
+		testFromFileSelfCheckWithOptions(t, "test_cnf/RTI_k3_n100_m429_499.cnf", opts)
+	}
+}
+
+func TestRestartsAreCounted(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 3 8",
+		"1 2 3 0", "1 2 -3 0", "1 -2 3 0", "1 -2 -3 0",
+		"-1 2 3 0", "-1 2 -3 0", "-1 -2 3 0", "-1 -2 -3 0",
+	}, t)
+	s := NewSolver(problem, Options{Restart: FixedRestarts, RestartInterval: 1})
+	if solution, _ := s.Solve(); solution.Status != Unsat {
+		t.Errorf("Expected unsat but got %v", solution)
+	}
+	stats := s.Stats()
+	if stats.Restarts == 0 || stats.Conflicts == 0 || stats.Learned == 0 {
+		t.Errorf("Expected restarts, conflicts and learned clauses, but got %+v", stats)
+	}
+	s = NewSolver(problem, Options{Restart: NoRestarts})
+	s.Solve()
+	stats = s.Stats()
+	if stats.Restarts != 0 {
+		t.Errorf("Expected no restarts, but got %+v", stats)
+	}
+}
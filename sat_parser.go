@@ -0,0 +1,277 @@
+// Parser for the DIMACS "sat" format of formulas, which converts them to
+// clauses by the Tseitin transformation.
+
+package s1t
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// formula is a node of a formula in the DIMACS "sat" format: a literal, or an
+// operator applied to sub-formulas.
+type formula struct {
+	op       string // "" for a literal, or one of "*", "+", "-", "xor", "="
+	literal  Literal
+	children []*formula
+}
+
+// isSatFormat returns true for the DIMACS formats of formulas: "sat", with
+// "x" if xor is allowed, and "e" if = is allowed.
+func isSatFormat(format string) bool {
+	switch format {
+	case "sat", "satx", "sate", "satex":
+		return true
+	}
+	return false
+}
+
+// parseSatFormula parses the formula that follows a "sat" spec line, and
+// converts it to clauses by the Tseitin transformation. It adds the new vars
+// to the spec, as NumAuxVariables.
+func parseSatFormula(text string, spec *ProblemSpec) ([]Clause, error) {
+	p := satParser{tokens: tokenizeSat(text), spec: spec}
+	f, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("Unexpected %q after the formula", p.tokens[p.pos])
+	}
+	t := tseitin{nextVar: VarNum(spec.NumVariables), trueLiteral: none}
+	t.assert(f)
+	spec.NumAuxVariables = int(t.nextVar) - spec.NumVariables
+	spec.NumVariables = int(t.nextVar)
+	spec.NumClauses = len(t.clauses)
+	return t.clauses, nil
+}
+
+// tokenizeSat splits the text into parentheses, operators and numbers.
+func tokenizeSat(text string) []string {
+	var tokens []string
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '-' && i+1 < len(text) && isDigit(text[i+1]), isDigit(c):
+			j := i + 1
+			for j < len(text) && isDigit(text[j]) {
+				j++
+			}
+			tokens = append(tokens, text[i:j])
+			i = j
+		case strings.HasPrefix(text[i:], "xor"):
+			tokens = append(tokens, "xor")
+			i += len("xor")
+		default:
+			tokens = append(tokens, text[i:i+1])
+			i++
+		}
+	}
+	return tokens
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+type satParser struct {
+	tokens []string
+	pos    int
+	spec   *ProblemSpec
+}
+
+// parse parses one formula.
+func (p *satParser) parse() (*formula, error) {
+	if p.pos == len(p.tokens) {
+		return nil, fmt.Errorf("Expected a formula, but the input ended")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token {
+	case "(":
+		f, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expect(")")
+	case "-":
+		f, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		return &formula{op: token, children: []*formula{f}}, nil
+	case "xor", "=":
+		if token == "xor" && !strings.Contains(p.spec.Format, "x") ||
+			token == "=" && !strings.Contains(p.spec.Format, "e") {
+			return nil, fmt.Errorf("Operator %q is not allowed in format %q", token, p.spec.Format)
+		}
+		fallthrough
+	case "*", "+":
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		f := &formula{op: token}
+		for p.pos < len(p.tokens) && p.tokens[p.pos] != ")" {
+			child, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			f.children = append(f.children, child)
+		}
+		return f, p.expect(")")
+	}
+	num, err := strconv.Atoi(token)
+	if err != nil || num == 0 {
+		return nil, fmt.Errorf("Expected a formula, but got %q", token)
+	}
+	if intAbs(num) > p.spec.NumVariables {
+		return nil, fmt.Errorf("Variable number %d goes beyond pre-declared num vars %d",
+			intAbs(num), p.spec.NumVariables)
+	}
+	return &formula{literal: FromDimacs(num)}, nil
+}
+
+func (p *satParser) expect(token string) error {
+	if p.pos == len(p.tokens) {
+		return fmt.Errorf("Expected %q, but the input ended", token)
+	}
+	if p.tokens[p.pos] != token {
+		return fmt.Errorf("Expected %q, but got %q", token, p.tokens[p.pos])
+	}
+	p.pos++
+	return nil
+}
+
+// tseitin converts formulas to clauses, with a new var for each operator which
+// is equivalent to its result.
+type tseitin struct {
+	nextVar     VarNum
+	trueLiteral int // literal of a var which is true, or none if not needed yet
+	clauses     []Clause
+}
+
+func (t *tseitin) newVar() Literal {
+	t.nextVar++
+	return Positive(t.nextVar - 1)
+}
+
+func (t *tseitin) addClause(literals ...Literal) {
+	var clause []Literal
+	for _, l := range literals {
+		if !containsLiteral(clause, l) {
+			clause = append(clause, l)
+		}
+	}
+	t.clauses = append(t.clauses, Clause{Literals: clause})
+}
+
+func containsLiteral(literals []Literal, l Literal) bool {
+	for _, other := range literals {
+		if other == l {
+			return true
+		}
+	}
+	return false
+}
+
+// assert adds clauses which make f true. Conjunctions and disjunctions at the
+// top are added directly, without new vars.
+func (t *tseitin) assert(f *formula) {
+	switch f.op {
+	case "*":
+		for _, child := range f.children {
+			t.assert(child)
+		}
+	case "+":
+		t.addClause(t.encodeAll(f.children)...)
+	default:
+		t.addClause(t.encode(f))
+	}
+}
+
+func (t *tseitin) encodeAll(formulas []*formula) []Literal {
+	literals := make([]Literal, len(formulas))
+	for i, f := range formulas {
+		literals[i] = t.encode(f)
+	}
+	return literals
+}
+
+// encode returns a literal which is equivalent to f.
+func (t *tseitin) encode(f *formula) Literal {
+	switch f.op {
+	case "":
+		return f.literal
+	case "-":
+		return t.encode(f.children[0]).Negate()
+	case "*":
+		return t.and(t.encodeAll(f.children))
+	case "+":
+		return t.or(t.encodeAll(f.children))
+	case "xor":
+		if len(f.children) == 0 {
+			return t.constant().Negate()
+		}
+		literals := t.encodeAll(f.children)
+		result := literals[0]
+		for _, l := range literals[1:] {
+			result = t.xor(result, l)
+		}
+		return result
+	default: // "="
+		literals := t.encodeAll(f.children)
+		negated := make([]Literal, len(literals))
+		for i, l := range literals {
+			negated[i] = l.Negate()
+		}
+		return t.or([]Literal{t.and(literals), t.and(negated)})
+	}
+}
+
+// constant returns a literal which is true.
+func (t *tseitin) constant() Literal {
+	if t.trueLiteral == none {
+		l := t.newVar()
+		t.addClause(l)
+		t.trueLiteral = int(l)
+	}
+	return Literal(t.trueLiteral)
+}
+
+// and returns a literal equivalent to the conjunction of the literals.
+func (t *tseitin) and(literals []Literal) Literal {
+	negated := make([]Literal, len(literals))
+	for i, l := range literals {
+		negated[i] = l.Negate()
+	}
+	return t.or(negated).Negate()
+}
+
+// or returns a literal equivalent to the disjunction of the literals.
+func (t *tseitin) or(literals []Literal) Literal {
+	if len(literals) == 1 {
+		return literals[0]
+	}
+	if len(literals) == 0 {
+		return t.constant().Negate()
+	}
+	x := t.newVar()
+	for _, l := range literals {
+		t.addClause(x, l.Negate())
+	}
+	t.addClause(append([]Literal{x.Negate()}, literals...)...)
+	return x
+}
+
+// xor returns a literal equivalent to a xor b.
+func (t *tseitin) xor(a, b Literal) Literal {
+	x := t.newVar()
+	t.addClause(x.Negate(), a, b)
+	t.addClause(x.Negate(), a.Negate(), b.Negate())
+	t.addClause(x, a.Negate(), b)
+	t.addClause(x, a, b.Negate())
+	return x
+}
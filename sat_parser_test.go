@@ -0,0 +1,146 @@
+package s1t
+
+import (
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// evalFormula returns the value of f for the assignment of vars in bits.
+func evalFormula(f *formula, bits int) bool {
+	switch f.op {
+	case "":
+		return (bits>>uint(f.literal.Var()))&1 == f.literal.AsInt()
+	case "-":
+		return !evalFormula(f.children[0], bits)
+	}
+	result := f.op == "*" || f.op == "="
+	for _, child := range f.children {
+		value := evalFormula(child, bits)
+		switch f.op {
+		case "*":
+			result = result && value
+		case "+":
+			result = result || value
+		case "xor":
+			result = result != value
+		case "=":
+			result = result && value == evalFormula(f.children[0], bits)
+		}
+	}
+	return result
+}
+
+// randomFormula returns a random formula over numVars vars, and its text.
+func randomFormula(rng *rand.Rand, numVars, depth int) (*formula, string) {
+	if depth == 0 || rng.Intn(4) == 0 {
+		num := 1 + rng.Intn(numVars)
+		if rng.Intn(2) == 0 {
+			num = -num
+		}
+		return &formula{literal: FromDimacs(num)}, strconv.Itoa(num)
+	}
+	op := []string{"*", "+", "-", "xor", "="}[rng.Intn(5)]
+	f := &formula{op: op}
+	n := rng.Intn(4)
+	if op == "-" {
+		n = 1
+	}
+	var texts []string
+	for i := 0; i < n; i++ {
+		child, text := randomFormula(rng, numVars, depth-1)
+		f.children = append(f.children, child)
+		texts = append(texts, text)
+	}
+	return f, op + "(" + strings.Join(texts, " ") + ")"
+}
+
+func TestSatFormat(t *testing.T) {
+	problem := inputToProblem([]string{
+		"c Example from the DIMACS spec",
+		"p sat 4",
+		"(*(+(1 3 -4)",
+		"   +(4)",
+		"   +(2 3)))",
+	}, t)
+	if problem.Spec.Format != "sat" || len(problem.Clauses) != 3 || problem.Spec.NumAuxVariables != 0 {
+		t.Errorf("Expected the 3 clauses without new vars, but got %v", problem)
+	}
+	solution := Solve(problem)
+	if solution.Status != Sat {
+		t.Fatalf("Expected sat, but got %v", solution)
+	}
+
+	problem = inputToProblem([]string{"p satex 3", "-(xor(1 2 =(2 3) -3))"}, t)
+	if problem.Spec.NumAuxVariables == 0 {
+		t.Errorf("Expected new vars, but got %v", problem.Spec)
+	}
+	solution = Solve(problem)
+	output := solution.Output(problem)
+	if strings.Count(output, "v ") != 3 {
+		t.Errorf("Expected only the input vars in the output, but got %q", output)
+	}
+}
+
+func TestSatFormatMatchesTruthTable(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const numVars = 5
+	for i := 0; i < 200; i++ {
+		f, text := randomFormula(rng, numVars, 4)
+		problem := inputToProblem([]string{"p satex " + strconv.Itoa(numVars), text}, t)
+		expected := 0
+		for bits := 0; bits < 1<<numVars; bits++ {
+			if evalFormula(f, bits) {
+				expected++
+			}
+		}
+		// The new vars are determined by the input vars, so the counts match.
+		if n := Count(problem); n.Cmp(big.NewInt(int64(expected))) != 0 {
+			t.Errorf("Formula %s, expected %d models but counted %v", text, expected, n)
+		}
+	}
+}
+
+func TestSatFormatErrorCases(t *testing.T) {
+	cases := []errorTestCase{
+		{
+			desc:                 "Xor without the x extension",
+			lines:                []string{"p sate 2", "xor(1 2)"},
+			expectedErrSubstring: "Operator \"xor\" is not allowed",
+		},
+		{
+			desc:                 "Equality without the e extension",
+			lines:                []string{"p satx 2", "=(1 2)"},
+			expectedErrSubstring: "Operator \"=\" is not allowed",
+		},
+		{
+			desc:                 "Unbalanced parentheses",
+			lines:                []string{"p sat 2", "*(+(1 2)"},
+			expectedErrSubstring: "Expected \")\", but the input ended",
+		},
+		{
+			desc:                 "Two formulas",
+			lines:                []string{"p sat 2", "+(1 2) 1"},
+			expectedErrSubstring: "Unexpected \"1\" after the formula",
+		},
+		{
+			desc:                 "Variable number greater than predeclared",
+			lines:                []string{"p sat 2", "+(1 -3)"},
+			expectedErrSubstring: "Variable number 3 goes beyond pre-declared",
+		},
+		{
+			desc:                 "Number of clauses in spec",
+			lines:                []string{"p sat 2 1", "+(1 2)"},
+			expectedErrSubstring: "Expected 3 fields of spec",
+		},
+	}
+	for _, c := range cases {
+		_, err := ParseDimacs(strings.NewReader(strings.Join(c.lines, "\n")))
+		if err == nil || !strings.Contains(err.Error(), c.expectedErrSubstring) {
+			t.Errorf("case %q, expected err string %q but got %v",
+				c.desc, c.expectedErrSubstring, err)
+		}
+	}
+}
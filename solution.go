@@ -7,25 +7,65 @@ import (
 	"strings"
 )
 
+// Status says whether a Problem is satisfiable, if known.
+type Status int
+
+const (
+	// Unknown means the solver gave up, e.g., it was cancelled or ran out of budget.
+	Unknown Status = iota
+	// Sat means the problem is satisfiable.
+	Sat
+	// Unsat means the problem is unsatisfiable.
+	Unsat
+)
+
+func (s Status) String() string {
+	switch s {
+	case Sat:
+		return "SAT"
+	case Unsat:
+		return "UNSAT"
+	default:
+		return "UNKNOWN"
+	}
+}
+
 // Solution for a Problem.
 type Solution struct {
-	IsSat      bool
+	Status     Status
+	IsSat      bool  // Status == Sat
 	Assignment []int // List from 0 to NumVars with the true/false/none assignment.
+	// Failed is set when Solver.SolveAssuming is Unsat because of the
+	// assumptions: a subset of them which is already unsat with the problem.
+	Failed []Literal
+	// Core is set when the problem is Unsat and Options.Core was given: the
+	// indices of input clauses which are unsat by themselves. Input clauses are
+	// those of Problem.Clauses followed by those from Solver.AddClause.
+	Core []ClauseNum
 }
 
 func unsat() Solution {
-	return Solution{}
+	return Solution{Status: Unsat}
 }
 
 func sat(a []int) Solution {
 	return Solution{
+		Status:     Sat,
 		IsSat:      true,
 		Assignment: a,
 	}
 }
 
-// Output returns the DIMACS format output for a solution of a problem.
+func unknown() Solution {
+	return Solution{Status: Unknown}
+}
+
+// Output returns the DIMACS format output for a solution of a problem. If the
+// problem has Symbols, the vars are named instead of numbered.
 func (s *Solution) Output(problem Problem) string {
+	if s.Status == Unknown {
+		return "s UNKNOWN\n"
+	}
 	satNum := 1
 	if !s.IsSat {
 		satNum = 0
@@ -37,7 +77,16 @@ func (s *Solution) Output(problem Problem) string {
 	if !s.IsSat {
 		return b.String()
 	}
-	for varNum, v := range s.Assignment {
+	numVars := problem.Spec.NumVariables - problem.Spec.NumAuxVariables
+	for varNum, v := range s.Assignment[:numVars] {
+		if problem.Symbols != nil {
+			not := ""
+			if v == 0 {
+				not = "~"
+			}
+			b.WriteString(fmt.Sprintf("v %s%s\n", not, problem.Symbols.Name(VarNum(varNum))))
+			continue
+		}
 		outNum := int(varNum + 1)
 		if v == 0 {
 			outNum = -outNum
@@ -51,21 +100,100 @@ func (s *Solution) Output(problem Problem) string {
 // Otherwise it returns false plus the first falsified clause.
 func (s *Solution) Satisfies(p Problem) (bool, *Clause) {
 	if !s.IsSat {
-		panic("Checking Satisfied() on an unsat solution")
+		panic("Checking Satisfied() on a solution that is not sat")
 	}
 	for _, c := range p.Clauses {
 		c := c
-		clauseSatisfied := false
-		for _, l := range c.Literals {
-			v := l.Var()
-			if s.Assignment[v] == l.AsInt() {
-				clauseSatisfied = true
-				break
+		if !s.satisfiesClause(c) {
+			return false, &c
+		}
+	}
+	return true, nil
+}
+
+// Cost returns the total weight of the problem's soft clauses which the
+// solution falsifies.
+func (s *Solution) Cost(p Problem) uint64 {
+	var cost uint64
+	for _, c := range p.Soft {
+		if !s.satisfiesClause(c.Clause) {
+			cost += c.Weight
+		}
+	}
+	return cost
+}
+
+// SatisfiesAtMosts checks if the solution satisfies the problem's cardinality
+// constraints, like Satisfies. Otherwise it returns false plus the first
+// falsified one.
+func (s *Solution) SatisfiesAtMosts(p Problem) (bool, *AtMost) {
+	for _, a := range p.AtMosts {
+		a := a
+		count := 0
+		for _, l := range a.Literals {
+			if s.Assignment[l.Var()] == l.AsInt() {
+				count++
+			}
+		}
+		if count > a.K {
+			return false, &a
+		}
+	}
+	return true, nil
+}
+
+// SatisfiesXors checks if the solution satisfies the problem's xor clauses,
+// like Satisfies. Otherwise it returns false plus the first falsified one.
+func (s *Solution) SatisfiesXors(p Problem) (bool, *XorClause) {
+	for _, x := range p.Xors {
+		x := x
+		parity := 0
+		for _, l := range x.Literals {
+			if s.Assignment[l.Var()] == l.AsInt() {
+				parity ^= 1
 			}
 		}
-		if !clauseSatisfied {
+		if parity != 1 {
+			return false, &x
+		}
+	}
+	return true, nil
+}
+
+// SatisfiesConstraints checks if the solution satisfies the problem's
+// pseudo-Boolean constraints, like Satisfies. Otherwise it returns false plus
+// the first falsified constraint.
+func (s *Solution) SatisfiesConstraints(p Problem) (bool, *PBConstraint) {
+	for _, c := range p.Constraints {
+		c := c
+		if s.sum(c.Terms) < c.Bound {
 			return false, &c
 		}
 	}
 	return true, nil
 }
+
+// Objective returns the value of the problem's objective: the sum of the
+// coefficients of its true literals.
+func (s *Solution) Objective(p Problem) int64 {
+	return s.sum(p.Objective)
+}
+
+func (s *Solution) sum(terms []PBTerm) int64 {
+	var sum int64
+	for _, t := range terms {
+		if s.Assignment[t.Literal.Var()] == t.Literal.AsInt() {
+			sum += t.Coefficient
+		}
+	}
+	return sum
+}
+
+func (s *Solution) satisfiesClause(c Clause) bool {
+	for _, l := range c.Literals {
+		if s.Assignment[l.Var()] == l.AsInt() {
+			return true
+		}
+	}
+	return false
+}
@@ -1,57 +1,422 @@
-// Basic Solver using Watched Literals.
+// Basic Solver using Watched Literals and conflict-driven clause learning (CDCL).
 
 package s1t
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
 const (
 	none = -1
+	// noReason is the reason recorded for decisions and unassigned variables.
+	noReason = ^ClauseNum(0)
+	// constraintReason is the reason recorded for literals implied by
+	// pseudo-Boolean constraints, until reasonOf explains them by a clause.
+	constraintReason = ^ClauseNum(1)
 )
 
+// Options configure how the solver searches.
+type Options struct {
+	// Heuristic picks decision variables (default EVSIDS).
+	Heuristic Heuristic
+	// Phase picks the value of decision variables (default PhaseSaving).
+	Phase Phase
+	// Restart picks when to restart (default LubyRestarts).
+	Restart RestartPolicy
+	// RestartInterval is the base number of conflicts between restarts for
+	// the Luby, geometric and fixed policies (default 100).
+	RestartInterval int
+	// ReduceInterval is the number of conflicts before the learned clause
+	// database is first reduced (default 2000). Later reductions are spaced
+	// further apart.
+	ReduceInterval int
+	// Seed, if non-zero, randomizes the initial variable order.
+	Seed int64
+
+	// Budgets for each call to Solve. Zero means unlimited.
+	// Solve returns an Unknown solution and ErrBudgetExhausted once any of
+	// them runs out.
+	MaxConflicts    int
+	MaxDecisions    int
+	MaxPropagations int
+	TimeLimit       time.Duration
+
+	// Verbosity is 0 for silence, 1 to log progress on each restart, and 2 to
+	// also log each learned clause database reduction.
+	Verbosity int
+	// Log receives progress messages as DIMACS comments (default os.Stderr).
+	Log io.Writer
+	// Tracer, if set, observes conflicts and learned clauses (for debugging).
+	Tracer Tracer
+
+	// Proof, if set, receives a DRAT proof of the learned and deleted clauses,
+	// which ends with the empty clause if the problem is unsat. It is relative
+	// to the problem clauses along with any from AddClause. BinaryProof
+	// selects the binary DRAT format instead of text.
+	Proof       io.Writer
+	BinaryProof bool
+
+	// Core computes Solution.Core when the problem is unsat, and MinimizeCore
+	// also shrinks it to a minimal unsatisfiable subset (see MinimizeCore).
+	// Cardinality, pseudo-Boolean and xor constraints are left out of both the
+	// proof and the core.
+	Core         bool
+	MinimizeCore bool
+
+	// RecoverXors finds the xor clauses which the clauses encode, and also
+	// propagates them by Gauss-Jordan elimination, along with Problem.Xors.
+	RecoverXors bool
+
+	// Preprocess simplifies the clauses before the search by subsumption,
+	// self-subsuming resolution, bounded variable elimination, substitution
+	// of equivalent literals and blocked clause elimination. Solutions are
+	// extended to satisfy the removed clauses, which are restored if their
+	// vars are used again, e.g., by AddClause or assumptions. It is ignored
+	// with Core or MinimizeCore, whose clause numbers refer to the problem.
+	Preprocess bool
+}
+
+// ErrBudgetExhausted is returned by Solver.Solve when it gives up because of
+// a limit set in its Options.
+var ErrBudgetExhausted = errors.New("Solver budget exhausted")
+
 // Solve determines if a given problem is unsat or sat (with an assignment).
 func Solve(problem Problem) Solution {
-	clauses := problem.Clauses
-	if hasEmptyClauses(clauses) {
-		return unsat()
+	return SolveWithOptions(problem, Options{})
+}
+
+// SolveWithOptions is like Solve, but configurable. If a budget in opts runs
+// out, the solution is Unknown.
+func SolveWithOptions(problem Problem, opts Options) Solution {
+	solution, _ := NewSolver(problem, opts).Solve()
+	return solution
+}
+
+// SolveContext is like Solve, but gives up with an Unknown solution and the
+// context's error if ctx is done first.
+func SolveContext(ctx context.Context, problem Problem) (Solution, error) {
+	return NewSolver(problem, Options{}).SolveContext(ctx)
+}
+
+// Solver searches for a solution of one Problem.
+type Solver struct {
+	// Problem clauses followed by learned clauses and clauses from AddClause,
+	// so a ClauseNum below len(Problem.Clauses) is an input clause (unless the
+	// clauses were preprocessed). Deleted learned clauses are empty.
+	clauses []Clause
+	learned learnedDB
+	trail   Trail
+	wls     watchedLiterals
+	pb      pbConstraints
+	xors    xorMatrix
+	elim    reconstruction
+	// Index into the trail of the next literal to propagate.
+	propagated int
+	decider    decider
+	restarter  restarter
+	seen       []bool // scratch space for analyze
+	// Scratch space for literalBlockDistance: levelStamps[level] == lbdStamp
+	// if the level was already counted.
+	levelStamps []int
+	lbdStamp    int
+	opts        Options
+	stats       Statistics
+	// False once the problem is known to be unsat.
+	ok bool
+	// Initial unit clauses were propagated.
+	propagatedUnits bool
+	// Assumptions for the current call to Solve, decided at the lowest levels,
+	// and the subset which failed.
+	assumptions []Literal
+	failed      []Literal
+	proof       *proofWriter // nil without Options.Proof
+	core        *coreTracker // nil without Options.Core
+	unsatCore   []ClauseNum
+	// MinimizeCore was done.
+	minimizedCore bool
+	// Budget limits for the current call to Solve.
+	conflictLimit    int
+	decisionLimit    int
+	propagationLimit int
+	deadline         time.Time
+	ctx              context.Context
+}
+
+// NewSolver prepares to solve problem, configured by opts.
+func NewSolver(problem Problem, opts Options) *Solver {
+	numVars := problem.Spec.NumVariables
+	clauses := make([]Clause, len(problem.Clauses))
+	copy(clauses, problem.Clauses)
+	if opts.Log == nil {
+		opts.Log = os.Stderr
 	}
-	assignments := initialAssignments(problem.Spec.NumVariables)
-	watchedLiterals := pickWatchedLiterals(problem.Spec.NumVariables, clauses)
-	if !initialUnitPropagate(clauses, assignments, watchedLiterals) {
-		return unsat()
+	var proof *proofWriter
+	if opts.Proof != nil {
+		proof = newProofWriter(opts.Proof, opts.BinaryProof)
 	}
-	assignedAtDepth := make([]VarNum, 0, problem.Spec.NumVariables)
-	if searchSolution(clauses, assignments, watchedLiterals, 0, 0, assignedAtDepth) {
-		return sat(assignments)
+	xors := problem.Xors
+	if opts.RecoverXors {
+		xors = append(recoverXors(clauses), xors...)
 	}
-	return unsat()
+	var stats Statistics
+	elim := reconstruction{stacked: make([]bool, numVars)}
+	if opts.Preprocess && !opts.Core && !opts.MinimizeCore {
+		clauses, elim = preprocess(clauses, numVars, frozenVars(problem, xors), proof, &stats)
+	}
+	s := &Solver{
+		clauses:     clauses,
+		learned:     newLearnedDB(len(clauses), opts.ReduceInterval),
+		trail:       newTrail(numVars),
+		wls:         pickWatchedLiterals(numVars, clauses),
+		pb:          newPBConstraints(numVars),
+		xors:        newXorMatrix(numVars),
+		elim:        elim,
+		decider:     newDecider(numVars, opts.Heuristic, opts.Phase, opts.Seed),
+		restarter:   newRestarter(opts.Restart, opts.RestartInterval),
+		seen:        make([]bool, numVars),
+		levelStamps: make([]int, numVars+1),
+		opts:        opts,
+		stats:       stats,
+		ok:          true,
+		proof:       proof,
+	}
+	if opts.Core || opts.MinimizeCore {
+		s.core = newCoreTracker(numVars)
+		for i := range clauses {
+			s.core.newClause()
+			s.core.addInput(ClauseNum(i))
+		}
+	}
+	for _, a := range problem.AtMosts {
+		s.addConstraint(a.constraint())
+	}
+	for _, c := range problem.Constraints {
+		s.addConstraint(c)
+	}
+	for _, x := range xors {
+		if conflict := s.addXor(x); conflict != noReason && s.ok {
+			s.setUnsat(conflict)
+		}
+	}
+	for i, clause := range clauses {
+		if clause.Empty() {
+			s.setUnsat(ClauseNum(i))
+			break
+		}
+	}
+	return s
 }
 
-func searchSolution(clauses []Clause, assignments []int, wls watchedLiterals, depth int,
-	searchFrom VarNum, assignedAtDepth []VarNum) bool {
-	varNum, hasUnassigned := nextUnassignedVariable(assignments, int(searchFrom))
-	if !hasUnassigned {
-		return true
+// Solve determines if the problem is unsat or sat (with an assignment).
+// Returns an Unknown solution and ErrBudgetExhausted if it gave up.
+func (s *Solver) Solve() (Solution, error) {
+	return s.SolveContext(context.Background())
+}
+
+// SolveContext is like Solve, but also gives up with an Unknown solution and
+// the context's error if ctx is done first.
+func (s *Solver) SolveContext(ctx context.Context) (Solution, error) {
+	return s.solve(ctx, nil)
+}
+
+func (s *Solver) solve(ctx context.Context, assumptions []Literal) (solution Solution, err error) {
+	if s.proof != nil {
+		defer func() {
+			if proofErr := s.proof.flush(); proofErr != nil && err == nil {
+				err = proofErr
+			}
+		}()
 	}
-	doRollbacks := func() {
-		for _, v := range assignedAtDepth {
-			assignments[v] = none
-		}
+	s.assumptions = assumptions
+	s.failed = nil
+	if !s.toLevelZero() {
+		return s.unsatSolution(), nil
+	}
+	s.setBudgets(ctx)
+	isSat, err := s.search()
+	s.assumptions = nil
+	if err != nil {
+		s.backjump(0)
+		return unknown(), err
+	}
+	if isSat {
+		assignment := make([]int, len(s.trail.assignments))
+		copy(assignment, s.trail.assignments)
+		s.elim.extend(assignment)
+		s.logf(1, "c sat after %d conflicts\n", s.stats.Conflicts)
+		return sat(assignment), nil
 	}
-	if tryAssign(clauses, varNum, Positive(varNum), assignments, wls, &assignedAtDepth) {
-		if searchSolution(clauses, assignments, wls, depth+1,
-			varNum, assignedAtDepth[len(assignedAtDepth):]) {
-			return true
+	if s.failed != nil {
+		s.logf(1, "c unsat under %d assumptions after %d conflicts\n",
+			len(s.failed), s.stats.Conflicts)
+		return Solution{Status: Unsat, Failed: s.failed}, nil
+	}
+	s.logf(1, "c unsat after %d conflicts\n", s.stats.Conflicts)
+	return s.unsatSolution(), nil
+}
+
+// unsatSolution returns Unsat, with the core if needed. The core is minimized
+// the first time.
+func (s *Solver) unsatSolution() Solution {
+	if s.opts.MinimizeCore && !s.minimizedCore {
+		s.minimizedCore = true
+		core, err := MinimizeCore(s.inputProblem(), s.unsatCore, s.opts)
+		if err != nil {
+			s.logf(1, "c stopped minimizing the core: %v\n", err)
 		}
+		s.logf(1, "c minimized the core from %d to %d clauses\n", len(s.unsatCore), len(core))
+		s.unsatCore = core
 	}
-	doRollbacks()
+	return Solution{Status: Unsat, Core: s.unsatCore}
+}
 
-	assignedAtDepth = assignedAtDepth[:0]
-	if tryAssign(clauses, varNum, Negative(varNum), assignments, wls, &assignedAtDepth) {
-		if searchSolution(clauses, assignments, wls, depth+1,
-			varNum, assignedAtDepth[len(assignedAtDepth):]) {
-			return true
+// Stats returns statistics about the work done so far.
+func (s *Solver) Stats() Statistics {
+	return s.stats
+}
+
+func (s *Solver) setBudgets(ctx context.Context) {
+	s.conflictLimit, s.decisionLimit, s.propagationLimit = 0, 0, 0
+	s.deadline = time.Time{}
+	s.ctx = ctx
+	if s.opts.MaxConflicts > 0 {
+		s.conflictLimit = s.stats.Conflicts + s.opts.MaxConflicts
+	}
+	if s.opts.MaxDecisions > 0 {
+		s.decisionLimit = s.stats.Decisions + s.opts.MaxDecisions
+	}
+	if s.opts.MaxPropagations > 0 {
+		s.propagationLimit = s.stats.Propagations + s.opts.MaxPropagations
+	}
+	if s.opts.TimeLimit > 0 {
+		s.deadline = time.Now().Add(s.opts.TimeLimit)
+	}
+}
+
+// checkBudgets returns an error if the search should give up.
+func (s *Solver) checkBudgets() error {
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	default:
+	}
+	if s.conflictLimit > 0 && s.stats.Conflicts >= s.conflictLimit {
+		return ErrBudgetExhausted
+	}
+	if s.decisionLimit > 0 && s.stats.Decisions >= s.decisionLimit {
+		return ErrBudgetExhausted
+	}
+	if s.propagationLimit > 0 && s.stats.Propagations >= s.propagationLimit {
+		return ErrBudgetExhausted
+	}
+	if !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		return ErrBudgetExhausted
+	}
+	return nil
+}
+
+func (s *Solver) logf(verbosity int, format string, args ...interface{}) {
+	if s.opts.Verbosity >= verbosity {
+		fmt.Fprintf(s.opts.Log, format, args...)
+	}
+}
+
+// search runs the CDCL loop: propagate, and on conflict learn a clause and
+// backjump, otherwise decide. Returns true if a satisfying assignment was
+// found, or an error if it gave up.
+//
+// The loop keeps all state on the trail, so the stack depth does not grow
+// with the number of decisions or the length of propagation chains.
+func (s *Solver) search() (bool, error) {
+	for {
+		if conflict := s.propagate(); conflict != noReason {
+			if !s.learnFromConflict(conflict) {
+				return false, nil
+			}
+			continue
 		}
+		if err := s.checkBudgets(); err != nil {
+			s.logf(1, "c gave up after %d conflicts: %v\n", s.stats.Conflicts, err)
+			return false, err
+		}
+		if s.restarter.shouldRestart() {
+			s.backjump(0)
+			s.restarter.restarted()
+			s.stats.Restarts++
+			s.logf(1, "c restart %d: conflicts %d, learned %d, fixed %d\n",
+				s.stats.Restarts, s.stats.Conflicts, s.learned.count, s.trail.Len())
+		}
+		if s.stats.Conflicts >= s.learned.nextReduce {
+			s.reduceLearned()
+			s.logf(2, "c reduced learned clauses to %d (deleted %d in total)\n",
+				s.learned.count, s.stats.Deleted)
+		}
+		if level := s.trail.DecisionLevel(); level < len(s.assumptions) {
+			if !s.assume(s.assumptions[level]) {
+				return false, nil
+			}
+			continue
+		}
+		if !s.decide() {
+			return true, nil
+		}
+	}
+}
+
+// decide opens a new decision level with the next decision literal.
+// Returns false if all variables are assigned.
+func (s *Solver) decide() bool {
+	decision, hasUnassigned := s.decider.next(s.trail.assignments)
+	if !hasUnassigned {
+		return false
 	}
-	doRollbacks()
-	return false
+	s.stats.Decisions++
+	s.trail.newDecisionLevel()
+	s.enqueue(decision, noReason)
+	return true
+}
+
+// learnFromConflict analyzes the conflict, backjumps and asserts the learned
+// clause. Returns false if the conflict is at level 0, so the problem is unsat.
+func (s *Solver) learnFromConflict(conflict ClauseNum) bool {
+	s.stats.Conflicts++
+	if s.opts.Tracer != nil {
+		s.opts.Tracer.Conflict(&s.trail, conflict, s.clauses[conflict])
+	}
+	if s.trail.DecisionLevel() == 0 {
+		s.setUnsat(conflict)
+		return false
+	}
+	learned, backjumpLevel := s.analyze(conflict)
+	if s.learned.explanation[conflict] {
+		s.freeExplanation(conflict)
+	}
+	lbd := s.literalBlockDistance(learned)
+	s.restarter.conflict(lbd)
+	s.backjump(backjumpLevel)
+	cnum := s.addLearnedClause(learned, lbd)
+	s.stats.Learned++
+	if s.core != nil {
+		s.core.learned(cnum)
+	}
+	if s.proof != nil {
+		s.proof.add(learned)
+	}
+	for _, l := range learned {
+		s.decider.bumpLearned(l)
+	}
+	s.decider.decay()
+	s.learned.decay()
+	if s.opts.Tracer != nil {
+		s.opts.Tracer.Learned(&s.trail, cnum, s.clauses[cnum])
+	}
+	s.enqueue(learned[0], cnum)
+	return true
 }
 
 func nextUnassignedVariable(assignments []int, searchFrom int) (VarNum, bool) {
@@ -63,62 +428,88 @@ func nextUnassignedVariable(assignments []int, searchFrom int) (VarNum, bool) {
 	return 0, false
 }
 
-// Try assigning v to satisfy literal l and return true if successful (false if contradicted).
-//
-// Updates assignments, wls and assigned in-out arguments.
-// May trigger a chain of unit clause propagation whose assignments
-// are tracked by assigned.
-func tryAssign(clauses []Clause, v VarNum, l Literal,
-	assignments []int, wls watchedLiterals, assigned *[]VarNum) bool {
-	if assignments[v] == none {
-		assignments[v] = l.AsInt()
-		*assigned = append(*assigned, v)
-	} else {
-		if assignments[v] != l.AsInt() {
-			return false
+// enqueue assigns l to true because of clause from (noReason for decisions),
+// to be propagated later. Returns false if l is already false.
+func (s *Solver) enqueue(l Literal, from ClauseNum) bool {
+	a := s.trail.assignments[l.Var()]
+	if a != none {
+		return a == l.AsInt()
+	}
+	s.trail.push(l, from)
+	if from != noReason {
+		s.stats.Propagations++
+		if s.core != nil && s.trail.DecisionLevel() == 0 {
+			s.core.fixed(l.Var(), s.clauses[from], from)
 		}
 	}
+	return true
+}
+
+// propagate does unit propagation of every assigned literal that was not yet
+// propagated, in trail order. Returns the falsified clause if this leads to a
+// conflict, or noReason.
+func (s *Solver) propagate() ClauseNum {
+	for s.propagated < s.trail.Len() {
+		l := s.trail.literals[s.propagated]
+		s.propagated++
+		// Constraints first, since backjump restores the slack of every
+		// propagated literal.
+		if conflict := s.propagatePB(l); conflict != noReason {
+			return conflict
+		}
+		if conflict := s.propagateXor(l); conflict != noReason {
+			return conflict
+		}
+		if conflict := s.propagateLiteral(l); conflict != noReason {
+			return conflict
+		}
+	}
+	return noReason
+}
+
+// propagateLiteral updates watched literals now that l is true, and enqueues
+// the literals of clauses that became unit.
+// Returns the falsified clause if there is a conflict, or noReason.
+func (s *Solver) propagateLiteral(l Literal) ClauseNum {
 	// l is fine, since it becomes true and satisfies watched literal invariants.
 	// ¬l will become false, so need to watch a different literal.
 	negatedL := l.Negate()
-	affectedClauses := wls.literalToClause[negatedL]
+	affectedClauses := s.wls.literalToClause[negatedL]
 	for i := 0; i < len(affectedClauses); {
 		cnum := affectedClauses[i]
-		watchedForC := wls.clauseToLiteral[cnum]
+		watchedForC := s.wls.clauseToLiteral[cnum]
 		otherWatchedLit := watchedForC.otherWatched(negatedL)
-		newLit := findNewWatchedLiteral(clauses, assignments, otherWatchedLit, cnum, negatedL)
+		otherA := s.trail.assignments[otherWatchedLit.Var()]
+		if otherA == otherWatchedLit.AsInt() {
+			// Already satisfied by the other watched literal.
+			i++
+			continue
+		}
+		newLit := findNewWatchedLiteral(s.clauses, s.trail.assignments, otherWatchedLit, cnum, negatedL)
 		if newLit == none {
 			// Only the other watched literal is available, in which case
 			// we'll violate the invariant and just keep the watch at the same spot.
 			// If we ever backtrack, the invariant will be restored.
 			// If the other watched literal
-			// - is true, the clause is already satisfied
-			// - is unassigned, then we've found a unit clause so attempt to
-			//   recursively do unit propagation
-			otherWatchedV := otherWatchedLit.Var()
-			otherA := assignments[otherWatchedV]
+			// - is unassigned, then we've found a unit clause so enqueue it
+			// - is false, then the clause is falsified
 			if otherA == none {
-				// it's unit clause -- try to propagate more
-				if !tryAssign(clauses, otherWatchedV, otherWatchedLit, assignments, wls, assigned) {
-					return false
-				}
+				s.enqueue(otherWatchedLit, cnum)
 			} else {
-				if otherA != otherWatchedLit.AsInt() {
-					return false
-				}
+				return cnum
 			}
 			i++
 		} else {
 			// switch watches to keep invariant
 			affectedClauses[i] = affectedClauses[len(affectedClauses)-1]
 			affectedClauses = affectedClauses[:len(affectedClauses)-1]
-			wls.literalToClause[negatedL] = affectedClauses
+			s.wls.literalToClause[negatedL] = affectedClauses
 
-			wls.literalToClause[newLit] = append(wls.literalToClause[newLit], cnum)
+			s.wls.literalToClause[newLit] = append(s.wls.literalToClause[newLit], cnum)
 			watchedForC.replaceOne(negatedL, newLit)
 		}
 	}
-	return true
+	return noReason
 }
 
 func findNewWatchedLiteral(
@@ -148,6 +539,108 @@ func findNewWatchedLiteral(
 	return none
 }
 
+// analyze derives a learned clause from the conflicting clause using the
+// first unique implication point (1-UIP) scheme.
+//
+// The asserting literal is at index 0 of the learned clause and, if there is
+// more than one literal, the literal with the highest remaining decision level
+// is at index 1. Also returns the level to backjump to.
+func (s *Solver) analyze(conflict ClauseNum) ([]Literal, int) {
+	learned := []Literal{none} // placeholder for the asserting literal
+	pathCount := 0
+	var p Literal = none
+	index := s.trail.Len() - 1
+	for {
+		if s.core != nil {
+			s.core.use(conflict)
+		}
+		if s.learned.isLearned(conflict) {
+			s.learned.bump(conflict)
+			s.learned.updateLBD(conflict, s.literalBlockDistance(s.clauses[conflict].Literals))
+		}
+		for _, q := range s.clauses[conflict].Literals {
+			if q == p {
+				continue
+			}
+			v := q.Var()
+			if s.seen[v] {
+				continue
+			}
+			if s.trail.level[v] == 0 {
+				if s.core != nil {
+					s.core.useUnit(v)
+				}
+				continue
+			}
+			s.seen[v] = true
+			s.decider.bumpConflict(v)
+			if s.trail.level[v] >= s.trail.DecisionLevel() {
+				pathCount++
+			} else {
+				learned = append(learned, q)
+			}
+		}
+		// Walk back to the next literal of the current level in the conflict.
+		for !s.seen[s.trail.literals[index].Var()] {
+			index--
+		}
+		p = s.trail.literals[index]
+		index--
+		s.seen[p.Var()] = false
+		pathCount--
+		if pathCount == 0 {
+			break
+		}
+		conflict = s.reasonOf(p.Var())
+	}
+	learned[0] = p.Negate()
+
+	backjumpLevel := 0
+	for i := 1; i < len(learned); i++ {
+		s.seen[learned[i].Var()] = false
+		if lvl := s.trail.level[learned[i].Var()]; lvl > backjumpLevel {
+			backjumpLevel = lvl
+			learned[1], learned[i] = learned[i], learned[1]
+		}
+	}
+	return learned, backjumpLevel
+}
+
+// literalBlockDistance returns the number of distinct decision levels among
+// the (assigned) literals, a measure of clause quality used by Glucose.
+func (s *Solver) literalBlockDistance(literals []Literal) int {
+	s.lbdStamp++
+	lbd := 0
+	for _, l := range literals {
+		level := s.trail.level[l.Var()]
+		if s.levelStamps[level] != s.lbdStamp {
+			s.levelStamps[level] = s.lbdStamp
+			lbd++
+		}
+	}
+	return lbd
+}
+
+// backjump unassigns every variable above the given decision level, saving
+// their phases and making them available for decisions again.
+func (s *Solver) backjump(level int) {
+	if s.trail.DecisionLevel() <= level {
+		return
+	}
+	start := s.trail.levelStarts[level]
+	for i, l := range s.trail.literals[start:] {
+		s.decider.unassigned(l)
+		if start+i < s.propagated {
+			s.unassignedPB(l)
+		}
+		if r := s.trail.reason[l.Var()]; r != noReason && r != constraintReason && s.learned.explanation[r] {
+			s.freeExplanation(r)
+		}
+	}
+	s.trail.backjump(level)
+	s.propagated = s.trail.Len()
+}
+
 // Initialize assignments to "none"
 func initialAssignments(numVars int) []int {
 	assignments := make([]int, numVars)
@@ -167,6 +660,35 @@ type watchedLiterals struct {
 	clauseToLiteral []*twoWatchedLiterals
 }
 
+// watch starts watching literals l1 and l2 of clause cnum.
+func (wls *watchedLiterals) watch(cnum ClauseNum, l1 Literal, l2 Literal) {
+	wls.literalToClause[l1] = append(wls.literalToClause[l1], cnum)
+	wls.literalToClause[l2] = append(wls.literalToClause[l2], cnum)
+	wls.clauseToLiteral[cnum] = &twoWatchedLiterals{l1, l2}
+}
+
+// detach stops watching clause cnum, e.g., before deleting it.
+func (wls *watchedLiterals) detach(cnum ClauseNum) {
+	watched := wls.clauseToLiteral[cnum]
+	if watched == nil {
+		return
+	}
+	wls.unwatch(watched.first, cnum)
+	wls.unwatch(watched.second, cnum)
+	wls.clauseToLiteral[cnum] = nil
+}
+
+func (wls *watchedLiterals) unwatch(l Literal, cnum ClauseNum) {
+	clauses := wls.literalToClause[l]
+	for i, c := range clauses {
+		if c == cnum {
+			clauses[i] = clauses[len(clauses)-1]
+			wls.literalToClause[l] = clauses[:len(clauses)-1]
+			return
+		}
+	}
+}
+
 func (wl *twoWatchedLiterals) otherWatched(l Literal) Literal {
 	if l == wl.first {
 		return wl.second
@@ -201,28 +723,26 @@ func pickWatchedLiterals(numVars int, clauses []Clause) watchedLiterals {
 	return watchedLiterals{l2c, c2l}
 }
 
-// Does initial unit clause propagation and returns true if formula is not falsified.
-// Since pickWatchLiterals skipped unit clauses, need to flush out the initial unit clauses.
-func initialUnitPropagate(clauses []Clause, assignments []int,
-	watchedLiterals watchedLiterals) bool {
-	for _, clause := range clauses {
+// Does initial unit clause propagation and returns the falsified clause, or
+// noReason. Since pickWatchLiterals skipped unit clauses, need to flush out the
+// initial unit clauses.
+func (s *Solver) initialUnitPropagate() ClauseNum {
+	for i, clause := range s.clauses {
 		if len(clause.Literals) == 1 {
-			l := clause.Literals[0]
-			v := l.Var()
-			assigned := make([]VarNum, 0, len(assignments)/4)
-			if !tryAssign(clauses, v, l, assignments, watchedLiterals, &assigned) {
-				return false
+			if !s.enqueue(clause.Literals[0], ClauseNum(i)) {
+				return ClauseNum(i)
 			}
 		}
 	}
-	return true
-}
-
-func hasEmptyClauses(clauses []Clause) bool {
-	for _, clause := range clauses {
-		if clause.Empty() {
-			return true
+	for ci := range s.pb.constraints {
+		if conflict := s.checkPB(ci); conflict != noReason {
+			return conflict
+		}
+	}
+	for ri := range s.xors.rows {
+		if conflict := s.checkXor(ri); conflict != noReason {
+			return conflict
 		}
 	}
-	return false
+	return s.propagate()
 }
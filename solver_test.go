@@ -1,10 +1,12 @@
 package s1t
 
 import (
+	"context"
 	"io"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -145,6 +147,147 @@ func TestBacktrack(t *testing.T) {
 	}
 }
 
+// Checks the learned clause and backjump level from 1-UIP conflict analysis.
+func TestAnalyzeFirstUIP(t *testing.T) {
+	problem := inputToProblem([]string{
+		"p cnf 5 3",
+		"-1 2 0", "-1 3 0", "-2 -3 -5 0",
+	}, t)
+	s := NewSolver(problem, Options{})
+	// Level 1 decides x5, level 2 decides x1 which propagates x2 and ¬x3.
+	for _, decision := range []Literal{Positive(4), Positive(0)} {
+		s.trail.newDecisionLevel()
+		s.enqueue(decision, noReason)
+		conflict := s.propagate()
+		if decision == Positive(4) {
+			if conflict != noReason {
+				t.Fatalf("Unexpected conflict %d after deciding %v", conflict, decision)
+			}
+			continue
+		}
+		if conflict == noReason {
+			t.Fatalf("Expected a conflict after deciding %v", decision)
+		}
+		learned, backjumpLevel := s.analyze(conflict)
+		expectedLearned := []Literal{Negative(0), Negative(4)}
+		if !cmp.Equal(learned, expectedLearned) {
+			t.Errorf("Expected learned clause %v, but got %v", expectedLearned, learned)
+		}
+		if backjumpLevel != 1 {
+			t.Errorf("Expected backjump to level 1, but got %d", backjumpLevel)
+		}
+	}
+}
+
+func TestBudgets(t *testing.T) {
+	input, err := os.Open("test_cnf/hole6.cnf")
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	defer input.Close()
+	problem := parseOrDie(input, t)
+	budgets := []Options{
+		{MaxConflicts: 10},
+		{MaxPropagations: 100},
+		{TimeLimit: time.Nanosecond},
+	}
+	for _, opts := range budgets {
+		s := NewSolver(problem, opts)
+		if solution, err := s.Solve(); err != ErrBudgetExhausted || solution.Status != Unknown {
+			t.Errorf("Options %+v, expected Unknown and ErrBudgetExhausted but got %v, %v",
+				opts, solution, err)
+		}
+	}
+	// Each call gets a fresh budget, and continues from the previous state.
+	s := NewSolver(problem, Options{MaxConflicts: 50})
+	for calls := 1; ; calls++ {
+		solution, err := s.Solve()
+		if err == nil {
+			if solution.Status != Unsat {
+				t.Errorf("Expected unsat but got %v", solution)
+			}
+			if calls == 1 {
+				t.Errorf("Expected to run out of budget at least once: %+v", s.Stats())
+			}
+			break
+		}
+		if err != ErrBudgetExhausted {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+}
+
+func TestSolveContext(t *testing.T) {
+	input, err := os.Open("test_cnf/hole6.cnf")
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	defer input.Close()
+	problem := parseOrDie(input, t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	solution, err := SolveContext(ctx, problem)
+	if err != context.Canceled || solution.Status != Unknown {
+		t.Errorf("Expected Unknown and context.Canceled, but got %v, %v", solution, err)
+	}
+	if out := solution.Output(problem); out != "s UNKNOWN\n" {
+		t.Errorf("Expected s UNKNOWN output, but got %q", out)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	solution, err = SolveContext(ctx, problem)
+	if err != nil || solution.Status != Unsat {
+		t.Errorf("Expected Unsat, but got %v, %v", solution, err)
+	}
+	solution, err = NewSolver(problem, Options{MaxDecisions: 5}).Solve()
+	if err != ErrBudgetExhausted || solution.Status != Unknown {
+		t.Errorf("Expected Unknown after 5 decisions, but got %v, %v", solution, err)
+	}
+}
+
+func TestSeedsAndLogging(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		var log strings.Builder
+		opts := Options{Seed: seed, RestartInterval: 5, Verbosity: 2, Log: &log}
+		testFromFileWithOptions(t, "test_cnf/hole6.cnf", opts, unsat())
+		testFromFileSelfCheckWithOptions(t, "test_cnf/blocksworld_medium.cnf", opts)
+		if !strings.Contains(log.String(), "c restart 1:") {
+			t.Errorf("Seed %d, expected restarts to be logged, but got %q", seed, log.String())
+		}
+	}
+}
+
+// A chain x1 => x2 => ... => xn, where xn must be false. Propagation is
+// iterative, so this does not need a stack frame per implication.
+func TestLongImplicationChain(t *testing.T) {
+	const n = 100000
+	clauses := []Clause{{Literals: []Literal{Negative(n - 1)}}}
+	for v := VarNum(0); v+1 < n; v++ {
+		clauses = append(clauses, Clause{Literals: []Literal{Negative(v), Positive(v + 1)}})
+	}
+	problem := Problem{
+		Spec:    ProblemSpec{Format: "cnf", NumVariables: n, NumClauses: len(clauses)},
+		Clauses: clauses,
+	}
+	solution := Solve(problem)
+	if ok, failed := solution.Satisfies(problem); !ok {
+		t.Fatalf("Solution does not satisfy %v", failed)
+	}
+	for v, a := range solution.Assignment {
+		if a != 0 {
+			t.Fatalf("Expected all vars to be false, but v%d is %d", v, a)
+		}
+	}
+
+	// With x1 forced true, the chain conflicts at level 0.
+	clauses = append(clauses, Clause{Literals: []Literal{Positive(0)}})
+	problem.Clauses = clauses
+	problem.Spec.NumClauses = len(clauses)
+	if solution := Solve(problem); solution.Status != Unsat {
+		t.Errorf("Expected unsat, but got %v", solution.Status)
+	}
+}
+
 // Randomly generated subset sum problem from http://toughsat.appspot.com/
 func TestSubsetSum2(t *testing.T) {
 	expectedSolution := sat([]int{1, 0, 1, 0, 0, 1, 1, 0, 0, 0, 1, 0, 1, 0, 0, 0, 0})
@@ -157,12 +300,15 @@ func TestSubsetSum2b(t *testing.T) {
 }
 
 func TestSubsetSum3(t *testing.T) {
+	// Has more than one solution, so the one found depends on the heuristic.
 	expectedSolution := sat([]int{
 		1, 1, 0, 1, 0, 1, 1, 0, 1, 1,
 		0, 1, 0, 1, 1, 0, 0, 0, 0, 1,
 		0, 0, 0, 1, 1, 1, 0, 0, 1, 0,
 		0, 0, 0})
-	testFromFile(t, "test_cnf/subsetsum3.cnf", expectedSolution)
+	testFromFileWithOptions(t, "test_cnf/subsetsum3.cnf", Options{Heuristic: Ordered},
+		expectedSolution)
+	testFromFileSelfCheck(t, "test_cnf/subsetsum3.cnf")
 }
 
 func TestQueen3(t *testing.T) {
@@ -230,6 +376,11 @@ func BenchmarkBlockworld(b *testing.B) {
 }
 
 func testFromFile(tb testing.TB, relativePath string, expectedSolutions ...Solution) {
+	testFromFileWithOptions(tb, relativePath, Options{}, expectedSolutions...)
+}
+
+func testFromFileWithOptions(tb testing.TB, relativePath string, opts Options,
+	expectedSolutions ...Solution) {
 	checker := func(tb testing.TB, problem Problem, solution Solution) {
 		matched := false
 		for _, es := range expectedSolutions {
@@ -243,10 +394,14 @@ func testFromFile(tb testing.TB, relativePath string, expectedSolutions ...Solut
 				relativePath, expectedSolutions, len(expectedSolutions), solution)
 		}
 	}
-	testFromFileWithChecker(tb, relativePath, checker)
+	testFromFileWithChecker(tb, relativePath, opts, checker)
 }
 
 func testFromFileSelfCheck(tb testing.TB, relativePath string) {
+	testFromFileSelfCheckWithOptions(tb, relativePath, Options{})
+}
+
+func testFromFileSelfCheckWithOptions(tb testing.TB, relativePath string, opts Options) {
 	checker := func(tb testing.TB, problem Problem, solution Solution) {
 		sat, failedClause := solution.Satisfies(problem)
 		if !sat {
@@ -254,18 +409,21 @@ func testFromFileSelfCheck(tb testing.TB, relativePath string) {
 				relativePath, failedClause, solution)
 		}
 	}
-	testFromFileWithChecker(tb, relativePath, checker)
+	testFromFileWithChecker(tb, relativePath, opts, checker)
 }
 
-func testFromFileWithChecker(
-	tb testing.TB, relativePath string, checker func(testing.TB, Problem, Solution)) {
+func testFromFileWithChecker(tb testing.TB, relativePath string, opts Options,
+	checker func(testing.TB, Problem, Solution)) {
 	input, err := os.Open(relativePath)
 	if err != nil {
 		tb.Fatalf("Failed to open test file: %v", err)
 	}
 	defer input.Close()
 	problem := parseOrDie(input, tb)
-	solution := Solve(problem)
+	solution, err := NewSolver(problem, opts).Solve()
+	if err != nil {
+		tb.Fatalf("Case %q, unexpected error %v", relativePath, err)
+	}
 	checker(tb, problem, solution)
 }
 
@@ -0,0 +1,33 @@
+// Counters describing a solver run.
+
+package s1t
+
+import (
+	"fmt"
+)
+
+// Statistics counts the work done by the solver.
+type Statistics struct {
+	Decisions    int
+	Propagations int
+	Conflicts    int
+	Restarts     int
+	Learned      int // learned clauses added
+	Deleted      int // learned clauses deleted by database reduction
+	// Preprocessing (see Options.Preprocess).
+	Eliminated   int // vars eliminated by resolution
+	Subsumed     int // clauses deleted by subsumption
+	Strengthened int // literals removed from clauses
+	Blocked      int // blocked clauses deleted
+	Substituted  int // vars replaced by an equivalent literal
+}
+
+// Output returns the statistics as DIMACS comment lines.
+func (st *Statistics) Output() string {
+	return fmt.Sprintf(
+		"c decisions %d\nc propagations %d\nc conflicts %d\nc restarts %d\n"+
+			"c learned %d\nc deleted %d\nc eliminated %d\nc subsumed %d\nc strengthened %d\n"+
+			"c blocked %d\nc substituted %d\n",
+		st.Decisions, st.Propagations, st.Conflicts, st.Restarts, st.Learned, st.Deleted,
+		st.Eliminated, st.Subsumed, st.Strengthened, st.Blocked, st.Substituted)
+}
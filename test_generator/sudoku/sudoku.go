@@ -14,11 +14,14 @@ import (
 	"time"
 
 	"github.com/jvoung/s1t"
+	"github.com/jvoung/s1t/cardinality"
 )
 
 var toCnf = flag.Bool("cnf", false, "convert unsolved board to cnf")
 var toBoard = flag.Bool("board", false, "parse assignment and print solved board")
 var endToEnd = flag.Bool("all", false, "unsolved board to cnf => solve => print")
+var encodingName = flag.String("encoding", "pairwise",
+	"encoding of exactly-one: pairwise, sequential, commander, totalizer, network, ladder or native")
 
 func main() {
 	flag.Parse()
@@ -39,15 +42,16 @@ func main() {
 				"given %d arguments\n",
 			len(args)))
 	}
+	encoding := parseEncoding(*encodingName)
 	if *toCnf {
 		board := ParseBoard(input)
-		WriteCNF(board, os.Stdout)
+		WriteCNF(board, encoding, os.Stdout)
 	} else if *toBoard {
 		board := ParseAssignments(input)
 		PrintBoard(board, "", os.Stdout)
 	} else if *endToEnd {
 		startTime := time.Now()
-		solveEndToEnd(input)
+		solveEndToEnd(input, encoding)
 		fmt.Printf("Solved in %f\n", time.Since(startTime).Seconds())
 	} else {
 		flag.Usage()
@@ -57,18 +61,27 @@ func main() {
 // Board holds the board values. 0 means blank, otherwise 1-9 are set.
 type Board [][]int
 
-func solveEndToEnd(input io.Reader) {
+func parseEncoding(name string) cardinality.Encoding {
+	for _, encoding := range cardinality.Encodings {
+		if encoding.String() == name {
+			return encoding
+		}
+	}
+	panic(fmt.Sprintf("Unknown encoding %q", name))
+}
+
+func solveEndToEnd(input io.Reader, encoding cardinality.Encoding) {
 	board := ParseBoard(input)
 	fmt.Println("Solving board:")
 	PrintBoard(board, "", os.Stdout)
 	fmt.Println("and got:")
-	solvedBoard := solveBoard(board)
+	solvedBoard := solveBoard(board, encoding)
 	PrintBoard(solvedBoard, "", os.Stdout)
 }
 
-func solveBoard(board Board) Board {
+func solveBoard(board Board, encoding cardinality.Encoding) Board {
 	buf := strings.Builder{}
-	WriteCNF(board, &buf)
+	WriteCNF(board, encoding, &buf)
 	problem, err := s1t.ParseDimacs(strings.NewReader(buf.String()))
 	if err != nil {
 		panic(err)
@@ -140,130 +153,95 @@ func PrintBoard(b Board, prefix string, w io.Writer) {
 	}
 }
 
-// WriteCNF writes CNF constraints of a given board to stdout.
-func WriteCNF(b Board, w io.Writer) {
+// WriteCNF writes CNF constraints of a given board to stdout, encoding the
+// exactly-one constraints with the given encoding.
+func WriteCNF(b Board, encoding cardinality.Encoding, w io.Writer) {
 	PrintBoard(b, "c ", w)
-	out := strings.Builder{}
-	clauses := 0
-	clauses += writePreassigned(b, &out)
-	clauses += writeRowConstraints(b, &out)
-	clauses += writeColConstraints(b, &out)
-	clauses += writeCellConstraints(b, &out)
-	clauses += writeBlockConstraints(b, &out)
-	clauses += writeSlopVariables(b, &out)
-	fmt.Fprintf(w, "p cnf %d %d\n", literalForCell(len(b)-1, len(b[0])-1, 9), clauses)
-	fmt.Fprint(w, out.String())
+	e := cardinality.NewEncoder(encoding, literalForCell(len(b)-1, len(b[0])-1, 9))
+	addPreassigned(b, e)
+	addRowConstraints(b, e)
+	addColConstraints(b, e)
+	addCellConstraints(b, e)
+	addBlockConstraints(b, encoding, e)
+	addSlopVariables(b, e)
+	if err := s1t.WriteDimacs(w, e.Problem()); err != nil {
+		panic(err)
+	}
+}
+
+func cellLiteral(r int, c int, v int) s1t.Literal {
+	return s1t.FromDimacs(literalForCell(r, c, v))
 }
 
-func writePreassigned(b Board, out *strings.Builder) int {
-	clauses := 0
+func addPreassigned(b Board, e *cardinality.Encoder) {
 	for r := 0; r < len(b); r++ {
 		for c := 0; c < len(b[0]); c++ {
 			if b[r][c] != 0 {
-				out.WriteString(fmt.Sprintf("%d 0\n", literalForCell(r, c, b[r][c])))
-				clauses++
+				e.AddClause(cellLiteral(r, c, b[r][c]))
 			}
 		}
 	}
-	return clauses
 }
 
-func writeSlopVariables(b Board, out *strings.Builder) int {
+func addSlopVariables(b Board, e *cardinality.Encoder) {
 	// Some variables we don't actually use (we multiply by 10 and 100 in literalToCell):
 	// * v == 0
 	// * c == 9
 	// May should hardwire them to false to save some time backtracking on variables
 	// that are essentially "don't care".
-	clauses := 0
 	for r := 0; r < len(b); r++ {
 		for c := 0; c < len(b[0]); c++ {
 			if c == 0 && r == 0 {
 				continue
 			}
-			out.WriteString(fmt.Sprintf("-%d 0\n", literalForCell(r, c, 0)))
-			clauses++
+			e.AddClause(cellLiteral(r, c, 0).Negate())
 		}
 	}
 	for r := 0; r < len(b)-1; r++ {
 		for v := 0; v <= 9; v++ {
-			out.WriteString(fmt.Sprintf("-%d 0\n", literalForCell(r, 9, v)))
-			clauses++
+			e.AddClause(cellLiteral(r, 9, v).Negate())
 		}
 	}
-	return clauses
 }
 
-func writeRowConstraints(b Board, out *strings.Builder) int {
-	clauses := 0
+func addRowConstraints(b Board, e *cardinality.Encoder) {
 	for r := 0; r < len(b); r++ {
 		for v := 1; v <= 9; v++ {
-			// At least one of the cols in the row have 'v' from 1-9
+			// Exactly one of the cols in the row have 'v' from 1-9
+			var literals []s1t.Literal
 			for c := 0; c < len(b[0]); c++ {
-				out.WriteString(fmt.Sprintf("%d ", literalForCell(r, c, v)))
-			}
-			out.WriteString("0\n")
-			clauses++
-			// At most one of the cols in the row have 'v' from 1-9
-			// Together, exactly one of the cols in the row have 'v'.
-			for c := 0; c < len(b[0])-1; c++ {
-				for c2 := c + 1; c2 < len(b[0]); c2++ {
-					out.WriteString(fmt.Sprintf("-%d -%d 0\n",
-						literalForCell(r, c, v),
-						literalForCell(r, c2, v)))
-					clauses++
-				}
+				literals = append(literals, cellLiteral(r, c, v))
 			}
+			e.Exactly(literals, 1)
 		}
 	}
-	return clauses
 }
 
-func writeColConstraints(b Board, out *strings.Builder) int {
-	clauses := 0
+func addColConstraints(b Board, e *cardinality.Encoder) {
 	for c := 0; c < len(b[0]); c++ {
 		for v := 1; v <= 9; v++ {
+			var literals []s1t.Literal
 			for r := 0; r < len(b); r++ {
-				out.WriteString(fmt.Sprintf("%d ", literalForCell(r, c, v)))
-			}
-			out.WriteString("0\n")
-			clauses++
-			for r := 0; r < len(b)-1; r++ {
-				for r2 := r + 1; r2 < len(b); r2++ {
-					out.WriteString(fmt.Sprintf("-%d -%d 0\n",
-						literalForCell(r, c, v),
-						literalForCell(r2, c, v)))
-					clauses++
-				}
+				literals = append(literals, cellLiteral(r, c, v))
 			}
+			e.Exactly(literals, 1)
 		}
 	}
-	return clauses
 }
 
-func writeCellConstraints(b Board, out *strings.Builder) int {
-	clauses := 0
+func addCellConstraints(b Board, e *cardinality.Encoder) {
 	for r := 0; r < len(b); r++ {
 		for c := 0; c < len(b[0]); c++ {
+			var literals []s1t.Literal
 			for v := 1; v <= 9; v++ {
-				out.WriteString(fmt.Sprintf("%d ", literalForCell(r, c, v)))
-			}
-			out.WriteString("0\n")
-			clauses++
-			for v := 1; v <= 8; v++ {
-				for v2 := v + 1; v2 <= 9; v2++ {
-					out.WriteString(fmt.Sprintf("-%d -%d 0\n",
-						literalForCell(r, c, v),
-						literalForCell(r, c, v2)))
-					clauses++
-				}
+				literals = append(literals, cellLiteral(r, c, v))
 			}
+			e.Exactly(literals, 1)
 		}
 	}
-	return clauses
 }
 
-func writeBlockConstraints(b Board, out *strings.Builder) int {
-	clauses := 0
+func addBlockConstraints(b Board, encoding cardinality.Encoding, e *cardinality.Encoder) {
 	toBlockRC := func(rb, cb, subx int) (int, int) {
 		r := rb*3 + (subx / 3)
 		c := cb*3 + (subx % 3)
@@ -272,12 +250,16 @@ func writeBlockConstraints(b Board, out *strings.Builder) int {
 	for rb := 0; rb < 3; rb++ {
 		for cb := 0; cb < 3; cb++ {
 			for v := 1; v <= 9; v++ {
+				var literals []s1t.Literal
 				for subx := 0; subx < 9; subx++ {
 					r, c := toBlockRC(rb, cb, subx)
-					out.WriteString(fmt.Sprintf("%d ", literalForCell(r, c, v)))
+					literals = append(literals, cellLiteral(r, c, v))
+				}
+				if encoding != cardinality.Pairwise {
+					e.Exactly(literals, 1)
+					continue
 				}
-				out.WriteString("0\n")
-				clauses++
+				e.AtLeast(literals, 1)
 				for subx := 0; subx < 8; subx++ {
 					r, c := toBlockRC(rb, cb, subx)
 					for sub2x := subx + 1; sub2x < 9; sub2x++ {
@@ -286,16 +268,12 @@ func writeBlockConstraints(b Board, out *strings.Builder) int {
 							// Same row/col already covered by row/col constraints
 							continue
 						}
-						out.WriteString(fmt.Sprintf("-%d -%d 0\n",
-							literalForCell(r, c, v),
-							literalForCell(r2, c2, v)))
-						clauses++
+						e.AddClause(cellLiteral(r, c, v).Negate(), cellLiteral(r2, c2, v).Negate())
 					}
 				}
 			}
 		}
 	}
-	return clauses
 }
 
 func literalForCell(r int, c int, v int) int {
@@ -324,7 +302,8 @@ func ParseAssignments(input io.Reader) Board {
 			if err != nil {
 				panic(fmt.Sprintf("Failed to parse variable line %v: %v", line, err))
 			}
-			if lit > 0 {
+			// Vars beyond the cells are for the cardinality encoding.
+			if lit > 0 && lit <= literalForCell(8, 8, 9) {
 				r, c, v := cellValForLit(lit)
 				if v > 0 {
 					b[r][c] = v
@@ -8,6 +8,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/jvoung/s1t"
+	"github.com/jvoung/s1t/cardinality"
 )
 
 func TestSolvableBoard(t *testing.T) {
@@ -35,7 +38,7 @@ func TestSolvableBoard(t *testing.T) {
 	if boardStr != expectedBoard {
 		t.Errorf("Parse failed, got %v instead of %v", boardStr, expectedBoard)
 	}
-	solvedBoard := solveBoard(board)
+	solvedBoard := solveBoard(board, cardinality.Pairwise)
 	boardStrBuilder.Reset()
 	PrintBoard(solvedBoard, "", &boardStrBuilder)
 	solvedBoardStr := boardStrBuilder.String()
@@ -56,6 +59,25 @@ func TestSolvableBoard(t *testing.T) {
 	}
 }
 
+func TestEncodings(t *testing.T) {
+	input, err := os.Open("test_data/board1.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer input.Close()
+	board := ParseBoard(input)
+	expected := strings.Builder{}
+	PrintBoard(solveBoard(board, cardinality.Pairwise), "", &expected)
+	for _, encoding := range cardinality.Encodings {
+		solved := strings.Builder{}
+		PrintBoard(solveBoard(board, encoding), "", &solved)
+		if solved.String() != expected.String() {
+			t.Errorf("Encoding %v, expected the solution %v, but got %v",
+				encoding, expected.String(), solved.String())
+		}
+	}
+}
+
 func BenchmarkTop95(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		testFromFileLines(b, "test_data/top95.txt")
@@ -105,5 +127,31 @@ func testFromFileLines(b *testing.B, fname string) {
 
 func parseAndSolve(input io.Reader) Board {
 	board := ParseBoard(input)
-	return solveBoard(board)
+	return solveBoard(board, cardinality.Pairwise)
+}
+
+func TestMultiSolution(t *testing.T) {
+	input, err := os.Open("test_data/multi_solution.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer input.Close()
+	buf := strings.Builder{}
+	WriteCNF(ParseBoard(input), cardinality.Pairwise, &buf)
+	problem, err := s1t.ParseDimacs(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	boards := make(map[string]bool)
+	count, err := s1t.Enumerate(problem, 3, func(solution s1t.Solution) bool {
+		solved := ParseAssignments(strings.NewReader(solution.Output(problem)))
+		boardStr := strings.Builder{}
+		PrintBoard(solved, "", &boardStr)
+		boards[boardStr.String()] = true
+		return true
+	})
+	if err != nil || count != 3 || len(boards) != 3 {
+		t.Errorf("Expected 3 different solved boards, but got %d (%d different), %v",
+			count, len(boards), err)
+	}
 }
@@ -0,0 +1,155 @@
+// Trail of assignments, recording the implication graph built during search.
+
+package s1t
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Trail is the solver's partial assignment in the order it was made.
+// Every assigned variable records the decision level it was assigned at and
+// the reason clause that forced it by unit propagation (decisions have none).
+// Together these form the implication graph used by conflict analysis.
+type Trail struct {
+	assignments []int       // none, or the var's value as in Literal.AsInt
+	level       []int       // decision level of each assigned var
+	reason      []ClauseNum // clause that forced each var, or noReason
+	index       []int       // index into literals of each assigned var
+	literals    []Literal   // assigned literals, in assignment order
+	levelStarts []int       // index into literals where each decision level starts
+}
+
+// TrailEntry describes one assignment on the Trail.
+type TrailEntry struct {
+	Literal Literal
+	Level   int
+	// Reason is the clause that forced Literal. Only valid if !Decision and
+	// the reason is a clause (see Trail.Reason).
+	Reason   ClauseNum
+	Decision bool
+}
+
+// Tracer observes the search, e.g., to debug the solver or to build explanations
+// and proofs from the implication graph. The Trail is only valid during the call.
+type Tracer interface {
+	// Conflict is called when clause c (number cnum) is falsified by the trail,
+	// before conflict analysis and backjumping.
+	Conflict(t *Trail, cnum ClauseNum, c Clause)
+	// Learned is called after backjumping, once learned clause c (number cnum)
+	// is added but before its asserting literal (c.Literals[0]) is assigned.
+	Learned(t *Trail, cnum ClauseNum, c Clause)
+}
+
+func newTrail(numVars int) Trail {
+	reason := make([]ClauseNum, numVars)
+	for i := range reason {
+		reason[i] = noReason
+	}
+	return Trail{
+		assignments: initialAssignments(numVars),
+		level:       make([]int, numVars),
+		reason:      reason,
+		index:       make([]int, numVars),
+		literals:    make([]Literal, 0, numVars),
+	}
+}
+
+// DecisionLevel returns the current decision level (0 before any decision).
+func (t *Trail) DecisionLevel() int {
+	return len(t.levelStarts)
+}
+
+// Len returns the number of assigned variables.
+func (t *Trail) Len() int {
+	return len(t.literals)
+}
+
+// Assigned returns true if the variable currently has a value.
+func (t *Trail) Assigned(v VarNum) bool {
+	return t.assignments[v] != none
+}
+
+// Value returns the literal of v that is currently true, if v is assigned.
+func (t *Trail) Value(v VarNum) (Literal, bool) {
+	switch t.assignments[v] {
+	case none:
+		return 0, false
+	case 0:
+		return Negative(v), true
+	default:
+		return Positive(v), true
+	}
+}
+
+// Level returns the decision level at which v was assigned.
+// Only valid if v is assigned.
+func (t *Trail) Level(v VarNum) int {
+	return t.level[v]
+}
+
+// Reason returns the clause that forced v by unit propagation.
+// Returns false if v is unassigned or was a decision, or if a pseudo-Boolean
+// constraint forced it and conflict analysis did not need a clause for it.
+func (t *Trail) Reason(v VarNum) (ClauseNum, bool) {
+	r := t.reason[v]
+	return r, r != noReason && r != constraintReason
+}
+
+// Entries returns a copy of the trail, in assignment order.
+func (t *Trail) Entries() []TrailEntry {
+	entries := make([]TrailEntry, len(t.literals))
+	for i, l := range t.literals {
+		v := l.Var()
+		entries[i] = TrailEntry{
+			Literal:  l,
+			Level:    t.level[v],
+			Reason:   t.reason[v],
+			Decision: t.reason[v] == noReason,
+		}
+	}
+	return entries
+}
+
+// String prints one assignment per line, grouped by decision level.
+func (t *Trail) String() string {
+	var b strings.Builder
+	for _, e := range t.Entries() {
+		if e.Decision {
+			b.WriteString(fmt.Sprintf("@%d %v (decision)\n", e.Level, e.Literal))
+		} else if e.Reason == constraintReason {
+			b.WriteString(fmt.Sprintf("@%d %v <- constraint\n", e.Level, e.Literal))
+		} else {
+			b.WriteString(fmt.Sprintf("@%d %v <- c%d\n", e.Level, e.Literal, e.Reason))
+		}
+	}
+	return b.String()
+}
+
+func (t *Trail) newDecisionLevel() {
+	t.levelStarts = append(t.levelStarts, len(t.literals))
+}
+
+// push assigns l to true at the current level, forced by clause from.
+func (t *Trail) push(l Literal, from ClauseNum) {
+	v := l.Var()
+	t.assignments[v] = l.AsInt()
+	t.level[v] = t.DecisionLevel()
+	t.reason[v] = from
+	t.index[v] = len(t.literals)
+	t.literals = append(t.literals, l)
+}
+
+// backjump unassigns every variable above the given decision level.
+func (t *Trail) backjump(level int) {
+	if t.DecisionLevel() <= level {
+		return
+	}
+	for i := len(t.literals) - 1; i >= t.levelStarts[level]; i-- {
+		v := t.literals[i].Var()
+		t.assignments[v] = none
+		t.reason[v] = noReason
+	}
+	t.literals = t.literals[:t.levelStarts[level]]
+	t.levelStarts = t.levelStarts[:level]
+}
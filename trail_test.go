@@ -0,0 +1,108 @@
+package s1t
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTrailLevelsAndReasons(t *testing.T) {
+	trail := newTrail(3)
+	trail.push(Negative(2), 0)
+	trail.newDecisionLevel()
+	trail.push(Positive(0), noReason)
+	trail.push(Positive(1), 4)
+	expectedEntries := []TrailEntry{
+		{Literal: Negative(2), Level: 0, Reason: 0},
+		{Literal: Positive(0), Level: 1, Reason: noReason, Decision: true},
+		{Literal: Positive(1), Level: 1, Reason: 4},
+	}
+	if !cmp.Equal(trail.Entries(), expectedEntries) {
+		t.Errorf("Expected entries %v, but got %v", expectedEntries, trail.Entries())
+	}
+	if r, ok := trail.Reason(1); !ok || r != 4 {
+		t.Errorf("Expected v1 to be forced by c4, but got %d %v", r, ok)
+	}
+	if _, ok := trail.Reason(0); ok {
+		t.Error("Expected decision v0 to have no reason")
+	}
+	expectedString := "@0 ¬v2 <- c0\n@1 v0 (decision)\n@1 v1 <- c4\n"
+	if trail.String() != expectedString {
+		t.Errorf("Expected %q, but got %q", expectedString, trail.String())
+	}
+
+	trail.backjump(0)
+	if trail.DecisionLevel() != 0 || trail.Len() != 1 {
+		t.Errorf("Expected only level 0 after backjump, but got %v", trail.String())
+	}
+	if trail.Assigned(0) || trail.Assigned(1) {
+		t.Error("Expected v0 and v1 to be unassigned after backjump")
+	}
+	if l, ok := trail.Value(2); !ok || l != Negative(2) {
+		t.Errorf("Expected ¬v2 to stay assigned, but got %v %v", l, ok)
+	}
+}
+
+// checkingTracer checks that every propagated literal on the trail is
+// implied by its reason clause.
+type checkingTracer struct {
+	t         *testing.T
+	clauses   map[ClauseNum]Clause
+	conflicts int
+	learned   int
+}
+
+func (ct *checkingTracer) Conflict(t *Trail, cnum ClauseNum, c Clause) {
+	ct.conflicts++
+	for _, l := range c.Literals {
+		if value, ok := t.Value(l.Var()); !ok || value != l.Negate() {
+			ct.t.Errorf("Literal %v of conflict c%d is not false: %v", l, cnum, t)
+		}
+	}
+	ct.checkReasons(t)
+}
+
+func (ct *checkingTracer) Learned(t *Trail, cnum ClauseNum, c Clause) {
+	ct.learned++
+	ct.clauses[cnum] = c
+	if t.Assigned(c.Literals[0].Var()) {
+		ct.t.Errorf("Asserting literal of learned c%d is already assigned: %v", cnum, t)
+	}
+	ct.checkReasons(t)
+}
+
+func (ct *checkingTracer) checkReasons(t *Trail) {
+	assignedBefore := make(map[Literal]bool)
+	for _, e := range t.Entries() {
+		if !e.Decision {
+			for _, l := range ct.clauses[e.Reason].Literals {
+				if l != e.Literal && !assignedBefore[l.Negate()] {
+					ct.t.Errorf("Reason c%d of %v is not unit: %v", e.Reason, e.Literal, t)
+				}
+			}
+		}
+		assignedBefore[e.Literal] = true
+	}
+}
+
+func TestTracerSeesImplicationGraph(t *testing.T) {
+	input, err := os.Open("test_cnf/hole6.cnf")
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	defer input.Close()
+	problem := parseOrDie(input, t)
+	tracer := &checkingTracer{t: t, clauses: make(map[ClauseNum]Clause)}
+	for i, c := range problem.Clauses {
+		tracer.clauses[ClauseNum(i)] = c
+	}
+	solution := SolveWithOptions(problem, Options{Tracer: tracer})
+	if solution.Status != Unsat {
+		t.Errorf("Expected unsat but got %v", solution)
+	}
+	if tracer.conflicts == 0 || tracer.learned == 0 {
+		t.Errorf("Expected conflicts and learned clauses, got %d and %d",
+			tracer.conflicts, tracer.learned)
+	}
+}
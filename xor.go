@@ -0,0 +1,413 @@
+// Xor clauses, propagated natively by incremental Gauss-Jordan elimination.
+// The xor clauses are the rows of a matrix over GF(2), each with a basic var
+// which is in no other row. While a row has unassigned vars, its basic var is
+// one of them, so a row implies its basic var once it is the only unassigned
+// var, and there are no other implications. When a basic var is assigned, its
+// row pivots to another unassigned var, eliminating it from the other rows.
+// Each row also watches one of its other vars, which is unassigned while the
+// row has two unassigned vars, so only the rows of the basic or watched var
+// are visited when a var is assigned. Each propagation (or conflict) is
+// explained by a clause implied by the row.
+
+package s1t
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// noVar stands for no var, e.g., when a row has no unassigned var.
+const noVar = ^VarNum(0)
+
+// xorRow is an xor of vars, as bits, which must have the parity.
+type xorRow struct {
+	vars   []uint64
+	parity int
+	basic  VarNum
+	watch  VarNum // another var than the basic var, or noVar if there is none
+}
+
+func newXorRow(x XorClause) xorRow {
+	// ¬x is x xor 1, so each negative literal flips the parity.
+	row := xorRow{parity: 1, watch: noVar}
+	for _, l := range x.Literals {
+		row.toggle(l.Var())
+		row.parity ^= 1 - l.AsInt()
+	}
+	return row
+}
+
+func (r *xorRow) has(v VarNum) bool {
+	i := int(v / 64)
+	return i < len(r.vars) && r.vars[i]&(1<<(v%64)) != 0
+}
+
+func (r *xorRow) toggle(v VarNum) {
+	for int(v/64) >= len(r.vars) {
+		r.vars = append(r.vars, 0)
+	}
+	r.vars[v/64] ^= 1 << (v % 64)
+}
+
+// add adds (xors) the other row into this one.
+func (r *xorRow) add(other *xorRow) {
+	for len(r.vars) < len(other.vars) {
+		r.vars = append(r.vars, 0)
+	}
+	for i, word := range other.vars {
+		r.vars[i] ^= word
+	}
+	r.parity ^= other.parity
+}
+
+// forEach calls f with each var of the row, until f returns false.
+func (r *xorRow) forEach(f func(v VarNum) bool) {
+	for i, word := range r.vars {
+		for word != 0 {
+			b := bits.TrailingZeros64(word)
+			word &= word - 1
+			if !f(VarNum(i*64 + b)) {
+				return
+			}
+		}
+	}
+}
+
+type xorMatrix struct {
+	rows     []xorRow
+	basicRow []int   // by var: the row of which it is the basic var, or none
+	watches  [][]int // by var: the rows which watch it
+}
+
+func newXorMatrix(numVars int) xorMatrix {
+	m := xorMatrix{basicRow: make([]int, numVars), watches: make([][]int, numVars)}
+	for v := range m.basicRow {
+		m.basicRow[v] = none
+	}
+	return m
+}
+
+// setWatch moves the watch of row ri to var w.
+func (m *xorMatrix) setWatch(ri int, w VarNum) {
+	row := &m.rows[ri]
+	if row.watch == w {
+		return
+	}
+	if row.watch != noVar {
+		ws := m.watches[row.watch]
+		for i, other := range ws {
+			if other == ri {
+				ws[i] = ws[len(ws)-1]
+				m.watches[row.watch] = ws[:len(ws)-1]
+				break
+			}
+		}
+	}
+	row.watch = w
+	if w != noVar {
+		m.watches[w] = append(m.watches[w], ri)
+	}
+}
+
+// AddXor adds an xor clause to the problem, like AddClause.
+// Returns an error if a literal's variable does not exist.
+func (s *Solver) AddXor(x XorClause) error {
+	for _, l := range x.Literals {
+		if int(l.Var()) >= s.NumVars() {
+			return fmt.Errorf("Variable %v goes beyond the %d vars", l.Var(), s.NumVars())
+		}
+	}
+	s.restore(x.Literals)
+	if !s.toLevelZero() {
+		return nil
+	}
+	if conflict := s.addXor(x); conflict != noReason {
+		s.setUnsat(conflict)
+		return nil
+	}
+	for ri := range s.xors.rows {
+		if conflict := s.checkXor(ri); conflict != noReason {
+			s.setUnsat(conflict)
+			return nil
+		}
+	}
+	if conflict := s.propagate(); conflict != noReason {
+		s.setUnsat(conflict)
+	}
+	return nil
+}
+
+// addXor eliminates the basic vars of the other rows from the xor clause, and
+// adds it as a row with an unassigned basic var. Returns a falsified clause if
+// the row has no unassigned vars and the wrong parity, or noReason.
+func (s *Solver) addXor(x XorClause) ClauseNum {
+	m := &s.xors
+	row := newXorRow(x)
+	for ri := range m.rows {
+		if row.has(m.rows[ri].basic) {
+			row.add(&m.rows[ri])
+		}
+	}
+	basic := noVar
+	row.forEach(func(v VarNum) bool {
+		if s.trail.assignments[v] == none {
+			basic = v
+			return false
+		}
+		return true
+	})
+	if basic == noVar {
+		// Redundant, or else its assigned vars falsify it.
+		if u, parity := s.xorValue(&row); u == noVar && parity != row.parity {
+			return s.explainXor(&row, noVar)
+		}
+		return noReason
+	}
+	row.basic = basic
+	m.rows = append(m.rows, row)
+	return s.pivot(len(m.rows)-1, basic)
+}
+
+// pivot makes v the basic var of row ri, eliminating it from the other rows,
+// and then updates the watches of the rows which changed. Returns a falsified
+// clause if one of them has a conflict, or noReason.
+func (s *Solver) pivot(ri int, v VarNum) ClauseNum {
+	m := &s.xors
+	row := &m.rows[ri]
+	if m.basicRow[row.basic] == ri {
+		m.basicRow[row.basic] = none
+	}
+	row.basic = v
+	m.basicRow[v] = ri
+	changed := []int{ri}
+	for other := range m.rows {
+		if other != ri && m.rows[other].has(v) {
+			m.rows[other].add(row)
+			changed = append(changed, other)
+		}
+	}
+	// Watch all the changed rows before checking any, which may conflict.
+	var check []int
+	for _, ri := range changed {
+		w, unassigned := s.xorWatch(&m.rows[ri])
+		m.setWatch(ri, w)
+		if !unassigned {
+			check = append(check, ri)
+		}
+	}
+	for _, ri := range check {
+		if conflict := s.checkXor(ri); conflict != noReason {
+			return conflict
+		}
+	}
+	return noReason
+}
+
+// xorWatch returns a var of the row other than the basic var, to watch: an
+// unassigned var and true, if there is one (preferring the current watch).
+// Otherwise it returns the var assigned last, which backjumping unassigns
+// before the others, or noVar if the row has no other var, and false.
+func (s *Solver) xorWatch(row *xorRow) (VarNum, bool) {
+	w := row.watch
+	if w != noVar && w != row.basic && row.has(w) && s.trail.assignments[w] == none {
+		return w, true
+	}
+	w = noVar
+	unassigned := false
+	row.forEach(func(v VarNum) bool {
+		switch {
+		case v == row.basic:
+		case s.trail.assignments[v] == none:
+			w = v
+			unassigned = true
+			return false
+		case w == noVar || s.trail.index[v] > s.trail.index[w]:
+			w = v
+		}
+		return true
+	})
+	return w, unassigned
+}
+
+// propagateXor pivots the row of l's var if it is basic, then moves the
+// watches on l's var, and enqueues the vars implied by the rows which have no
+// other var to watch.
+// Returns the falsified clause if there is a conflict, or noReason.
+func (s *Solver) propagateXor(l Literal) ClauseNum {
+	m := &s.xors
+	if len(m.rows) == 0 {
+		return noReason
+	}
+	v := l.Var()
+	if ri := m.basicRow[v]; ri != none {
+		u, _ := s.xorValue(&m.rows[ri])
+		if u == noVar {
+			if conflict := s.checkXor(ri); conflict != noReason {
+				return conflict
+			}
+		} else if conflict := s.pivot(ri, u); conflict != noReason {
+			return conflict
+		}
+	}
+	ws := m.watches[v]
+	kept := ws[:0]
+	for i, ri := range ws {
+		w, unassigned := s.xorWatch(&m.rows[ri])
+		if w == v {
+			kept = append(kept, ri)
+		} else {
+			m.rows[ri].watch = w
+			m.watches[w] = append(m.watches[w], ri)
+		}
+		if unassigned {
+			continue
+		}
+		if conflict := s.checkXor(ri); conflict != noReason {
+			m.watches[v] = append(kept, ws[i+1:]...)
+			return conflict
+		}
+	}
+	m.watches[v] = kept
+	return noReason
+}
+
+// xorValue returns an unassigned var of the row (other than the basic var, if
+// there are others), or none, and the parity of the row's assigned vars.
+func (s *Solver) xorValue(row *xorRow) (VarNum, int) {
+	unassigned := noVar
+	parity := 0
+	row.forEach(func(v VarNum) bool {
+		switch a := s.trail.assignments[v]; {
+		case a != none:
+			parity ^= a
+		case unassigned == noVar || unassigned == row.basic:
+			unassigned = v
+		}
+		return true
+	})
+	return unassigned, parity
+}
+
+// checkXor enqueues the only unassigned var of row ri, if there is one.
+// Returns a falsified clause if the row has no unassigned vars and the wrong
+// parity, or noReason.
+func (s *Solver) checkXor(ri int) ClauseNum {
+	row := &s.xors.rows[ri]
+	unassigned := 0
+	u := noVar
+	parity := 0
+	row.forEach(func(v VarNum) bool {
+		if a := s.trail.assignments[v]; a != none {
+			parity ^= a
+		} else {
+			unassigned++
+			u = v
+		}
+		return unassigned < 2
+	})
+	switch unassigned {
+	case 0:
+		if parity != row.parity {
+			return s.explainXor(row, noVar)
+		}
+	case 1:
+		// Usually the basic var, unless that was enqueued but not propagated.
+		value := row.parity ^ parity
+		s.enqueue(Literal(2*int(u)+value), s.explainXor(row, u))
+	}
+	return noReason
+}
+
+// explainXor adds a clause implied by the row, as the reason for its value of
+// var u: u's literal, and the false literal of each other var. With u of none,
+// the clause is falsified, to explain a conflict.
+func (s *Solver) explainXor(row *xorRow, u VarNum) ClauseNum {
+	var literals []Literal
+	parity := 0
+	row.forEach(func(v VarNum) bool {
+		if v != u {
+			a := s.trail.assignments[v]
+			parity ^= a
+			literals = append(literals, Literal(2*int(v)+1-a))
+		}
+		return true
+	})
+	if u == noVar {
+		return s.addExplanation(literals)
+	}
+	l := Literal(2*int(u) + (row.parity ^ parity))
+	return s.addExplanation(append([]Literal{l}, literals...))
+}
+
+// maxRecoveredXorSize limits the xors found by recoverXors, which need
+// 2^(size-1) clauses each.
+const maxRecoveredXorSize = 6
+
+// recoverXors finds xor clauses which are encoded by the clauses: an xor of
+// k literals is the 2^(k-1) clauses over its vars that forbid the assignments
+// of the wrong parity.
+func recoverXors(clauses []Clause) []XorClause {
+	// The sign patterns of the clauses over each set of vars, by the parity of
+	// their number of negative literals.
+	type group struct {
+		vars     []VarNum
+		patterns [2]map[uint]bool
+	}
+	groups := make(map[string]*group)
+	var keys []string
+	for _, c := range clauses {
+		k := len(c.Literals)
+		if k < 2 || k > maxRecoveredXorSize {
+			continue
+		}
+		literals := append([]Literal{}, c.Literals...)
+		sort.Slice(literals, func(i, j int) bool { return literals[i] < literals[j] })
+		var key []byte
+		var vars []VarNum
+		var pattern uint
+		negatives := 0
+		duplicate := false
+		for i, l := range literals {
+			if i > 0 && l.Var() == literals[i-1].Var() {
+				duplicate = true
+			}
+			key = append(key, fmt.Sprintf("%d ", l.Var())...)
+			vars = append(vars, l.Var())
+			if l.AsInt() == 0 {
+				pattern |= 1 << uint(i)
+				negatives++
+			}
+		}
+		if duplicate {
+			continue
+		}
+		g, ok := groups[string(key)]
+		if !ok {
+			g = &group{vars: vars, patterns: [2]map[uint]bool{{}, {}}}
+			groups[string(key)] = g
+			keys = append(keys, string(key))
+		}
+		g.patterns[negatives%2][pattern] = true
+	}
+	var xors []XorClause
+	for _, key := range keys {
+		g := groups[key]
+		for negatives, patterns := range g.patterns {
+			if len(patterns) != 1<<uint(len(g.vars)-1) {
+				continue
+			}
+			// The clauses forbid the assignments whose parity is that of their
+			// number of negative literals, so the xor has the other parity.
+			x := XorClause{}
+			for i, v := range g.vars {
+				x.Literals = append(x.Literals, Positive(v))
+				if i == 0 && negatives == 1 {
+					x.Literals[0] = Negative(v)
+				}
+			}
+			xors = append(xors, x)
+		}
+	}
+	return xors
+}
@@ -0,0 +1,186 @@
+package s1t
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// randomXorProblem returns a problem with random xor clauses along with
+// random clauses.
+func randomXorProblem(rng *rand.Rand, numVars, numClauses, numXors int) Problem {
+	problem := randomProblem(rng, numVars, numClauses)
+	for i := 0; i < numXors; i++ {
+		var x XorClause
+		for _, v := range rng.Perm(numVars)[:1+rng.Intn(4)] {
+			l := Positive(VarNum(v))
+			if rng.Intn(2) == 0 {
+				l = l.Negate()
+			}
+			x.Literals = append(x.Literals, l)
+		}
+		problem.Xors = append(problem.Xors, x)
+	}
+	return problem
+}
+
+func bruteForceXorSat(problem Problem) bool {
+	n := problem.Spec.NumVariables
+	for bits := 0; bits < 1<<uint(n); bits++ {
+		solution := Solution{Status: Sat, IsSat: true, Assignment: make([]int, n)}
+		for v := range solution.Assignment {
+			solution.Assignment[v] = (bits >> uint(v)) & 1
+		}
+		if ok, _ := solution.Satisfies(problem); !ok {
+			continue
+		}
+		if ok, _ := solution.SatisfiesXors(problem); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func TestXorClauses(t *testing.T) {
+	rng := rand.New(rand.NewSource(22))
+	for i := 0; i < 400; i++ {
+		problem := randomXorProblem(rng, 9, rng.Intn(20), 1+rng.Intn(8))
+		expectSat := bruteForceXorSat(problem)
+		for _, opts := range []Options{{}, {Core: true, ReduceInterval: 1}} {
+			solution, err := NewSolver(problem, opts).Solve()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if solution.IsSat != expectSat {
+				t.Fatalf("Expected sat %v, but got %v for %+v", expectSat, solution, problem)
+			}
+			if !expectSat {
+				continue
+			}
+			if ok, c := solution.Satisfies(problem); !ok {
+				t.Errorf("Solution %v falsifies clause %v", solution, c)
+			}
+			if ok, x := solution.SatisfiesXors(problem); !ok {
+				t.Errorf("Solution %v falsifies xor clause %v", solution, x)
+			}
+		}
+	}
+}
+
+// parityChain returns xor clauses that v0 differs from v1, v1 from v2, and so
+// on around a cycle of n vars, which is unsat for odd n.
+func parityChain(n int) []XorClause {
+	var xors []XorClause
+	for v := 0; v < n; v++ {
+		xors = append(xors, XorClause{Literals: []Literal{Positive(VarNum(v)), Positive(VarNum((v + 1) % n))}})
+	}
+	return xors
+}
+
+func TestXorEliminationWithoutSearch(t *testing.T) {
+	for _, n := range []int{101, 100} {
+		problem := Problem{Spec: ProblemSpec{NumVariables: n}, Xors: parityChain(n)}
+		s := NewSolver(problem, Options{})
+		solution, err := s.Solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expectSat := n%2 == 0; solution.IsSat != expectSat {
+			t.Errorf("Expected sat %v for a chain of %d, but got %v", expectSat, n, solution)
+		}
+		if stats := s.Stats(); stats.Conflicts != 0 {
+			t.Errorf("Expected no conflicts for a chain of %d, but got %d", n, stats.Conflicts)
+		}
+	}
+}
+
+func TestXorWatches(t *testing.T) {
+	rng := rand.New(rand.NewSource(22))
+	for i := 0; i < 50; i++ {
+		problem := randomXorProblem(rng, 12, 10, 6)
+		s := NewSolver(problem, Options{})
+		if _, err := s.SolveAssuming([]Literal{Positive(VarNum(rng.Intn(12)))}); err != nil {
+			t.Fatal(err)
+		}
+		// Each row watches a var other than its basic var, and is in the
+		// watches of that var only.
+		m := &s.xors
+		for ri, row := range m.rows {
+			if row.watch == noVar {
+				continue
+			}
+			if row.watch == row.basic || !row.has(row.watch) {
+				t.Errorf("Row %d watches %v, which is not one of its other vars", ri, row.watch)
+			}
+		}
+		for v, ws := range m.watches {
+			for _, ri := range ws {
+				if m.rows[ri].watch != VarNum(v) {
+					t.Errorf("Row %d is in the watches of %v, but watches %v", ri, v, m.rows[ri].watch)
+				}
+			}
+		}
+	}
+}
+
+func TestRecoverXors(t *testing.T) {
+	x1, x2, x3 := Positive(0), Positive(1), Positive(2)
+	clauses := []Clause{
+		// x1 xor x2 xor x3, with one clause per pattern of an even number of
+		// negations, in any order.
+		{Literals: []Literal{x3, x2, x1}},
+		{Literals: []Literal{x1.Negate(), x2.Negate(), x3}},
+		{Literals: []Literal{x1, x2.Negate(), x3.Negate()}},
+		{Literals: []Literal{x1.Negate(), x2, x3.Negate()}},
+		// ¬(x1 xor x2), which is missing a clause.
+		{Literals: []Literal{x1.Negate(), x2}},
+		{Literals: []Literal{x1, x2.Negate()}},
+		{Literals: []Literal{x2, x3}},
+	}
+	expected := []XorClause{
+		{Literals: []Literal{x1, x2, x3}},
+		{Literals: []Literal{x1.Negate(), x2}},
+	}
+	if diff := cmp.Diff(expected, recoverXors(clauses)); diff != "" {
+		t.Errorf("recoverXors differs (-want +got):\n%s", diff)
+	}
+
+	// The parity chain as clauses is only found unsat without search after
+	// recovering the xors.
+	problem := Problem{Spec: ProblemSpec{NumVariables: 51}}
+	for _, x := range parityChain(51) {
+		a, b := x.Literals[0], x.Literals[1]
+		problem.Clauses = append(problem.Clauses,
+			Clause{Literals: []Literal{a, b}}, Clause{Literals: []Literal{a.Negate(), b.Negate()}})
+	}
+	s := NewSolver(problem, Options{RecoverXors: true})
+	if solution, _ := s.Solve(); solution.Status != Unsat {
+		t.Errorf("Expected unsat, but got %v", solution)
+	}
+	if stats := s.Stats(); stats.Conflicts != 0 {
+		t.Errorf("Expected no conflicts, but got %d", stats.Conflicts)
+	}
+}
+
+func TestAddXor(t *testing.T) {
+	x, y := Positive(0), Positive(1)
+	s := NewSolver(Problem{Spec: ProblemSpec{NumVariables: 2}}, Options{})
+	if err := s.AddXor(XorClause{Literals: []Literal{x, y}}); err != nil {
+		t.Fatal(err)
+	}
+	solution, _ := s.Solve()
+	if !solution.IsSat || solution.Assignment[0] == solution.Assignment[1] {
+		t.Fatalf("Expected x and y to differ, but got %v", solution)
+	}
+	// x and y are equal.
+	if err := s.AddXor(XorClause{Literals: []Literal{x.Negate(), y}}); err != nil {
+		t.Fatal(err)
+	}
+	if solution, _ := s.Solve(); solution.Status != Unsat {
+		t.Errorf("Expected unsat, but got %v", solution)
+	}
+	if err := s.AddXor(XorClause{Literals: []Literal{Positive(2)}}); err == nil {
+		t.Errorf("Expected an error for a var beyond the solver's vars")
+	}
+}